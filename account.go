@@ -0,0 +1,162 @@
+/*
+ * SejongTelecom 코어기술개발팀
+ * @author JinSan
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+// SetupAccount is 지갑형 계정 등록 및 guardian 설정
+// 계정 주소는 처음 등록한 키의 지갑주소로 고정된다.
+// transjdata - wallet.AccountConfig
+func (cc *Chaincode) SetupAccount(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+
+	cfg := wallet.AccountConfig{}
+	decoder := json.NewDecoder(strings.NewReader(walletParams.Jdata))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.UnMarshalErrorType, "AccountConfig", err.Error()))
+	}
+
+	account, err := wallet.SetupAccount(stub, walletParams, cfg)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return accountResponse(account)
+}
+
+// RotateKey is 지갑형 서명 키 교체 (교체 전 키로 서명)
+// transdata - newkeyaddress
+func (cc *Chaincode) RotateKey(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	params := walletParams.Params
+	if len(params) != 1 || params[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "rotateKey", "transdata must be newkeyaddress"))
+	}
+
+	account, err := wallet.RotateKey(stub, walletParams, params[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return accountResponse(account)
+}
+
+// ApproveRecovery is 지갑형 guardian 복구 승인 (guardian 이 서명)
+// transdata - accountaddress, newkeyaddress
+func (cc *Chaincode) ApproveRecovery(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	params := walletParams.Params
+	if len(params) != 2 || params[0] == "" || params[1] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "approveRecovery", "transdata must be accountaddress,newkeyaddress"))
+	}
+
+	if err := wallet.RequireOwnKey(walletParams); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := wallet.RequireCanonicalSig(walletParams); err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	now, err := txSeconds(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	account, err := wallet.ApproveRecovery(stub, walletParams.Address, params[0], params[1], now)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return accountResponse(account)
+}
+
+// CancelRecovery is 지갑형 guardian 복구 취소 (계정 키로 서명)
+// transdata - 없음
+func (cc *Chaincode) CancelRecovery(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+
+	account, err := wallet.CancelRecovery(stub, walletParams)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return accountResponse(account)
+}
+
+// ExecuteRecovery is 대기 시간이 지난 guardian 복구 실행 (누구나 호출 가능)
+// 대기 시간은 proposal timestamp 로 확인하므로 키 소유자의 취소 시간을 보장하지 않는다.
+// params - accountaddress
+func (cc *Chaincode) ExecuteRecovery(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 || args[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "executeRecovery", "incorrect number of arguments, expecting account address"))
+	}
+
+	now, err := txSeconds(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	account, err := wallet.ExecuteRecovery(stub, args[0], now)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return accountResponse(account)
+}
+
+// GetAccount is 계정 레지스트리 조회 (JSON)
+// params - accountaddress
+func (cc *Chaincode) GetAccount(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 || args[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "getAccount", "incorrect number of arguments, expecting account address"))
+	}
+
+	account, err := wallet.GetAccount(stub, args[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if account == nil {
+		return model.ErrorResponse(model.NewCustomError(model.AccountErrorType, args[0], "account is not registered"))
+	}
+	return accountResponse(account)
+}
+
+func accountResponse(account *wallet.Account) sc.Response {
+	accountBytes, err := json.Marshal(account)
+	if err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.MarshalErrorType, "Account", err.Error()))
+	}
+	return shim.Success(accountBytes)
+}
+
+// txSeconds 는 트랜잭션 시각 (unix 초)
+func txSeconds(stub shim.ChaincodeStubInterface) (int64, error) {
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return 0, model.NewCustomError(model.TxTimeStampErrorType, "TxTimestamp", err.Error())
+	}
+	return txTimestamp.GetSeconds(), nil
+}
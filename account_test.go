@@ -0,0 +1,137 @@
+/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+func TestRotateKey(t *testing.T) {
+	stub := shim.NewMockStub(testChaincodeName, new(Chaincode))
+	alice, device := newTestWallet(t), newTestWallet(t)
+	aliceAddr, deviceAddr := alice.address(t, stub), device.address(t, stub)
+	seedBalance(t, stub, aliceAddr, 100)
+
+	if status, msg := alice.call(t, stub, "rotateKey", wallet.WalletMeta{Transdata: "bob"}); status != model.StatusCode(model.AddressErrorType) {
+		t.Errorf("rotate to malformed key = %d %s", status, msg)
+	}
+	if status, msg := alice.call(t, stub, "rotateKey", wallet.WalletMeta{Transdata: deviceAddr}); status != shim.OK {
+		t.Fatal("rotateKey failed", msg)
+	}
+
+	// 새 키는 계정 주소로 서명하고, 교체된 키는 바로 거부된다.
+	if addr := device.address(t, stub); addr != aliceAddr {
+		t.Errorf("rotated key address %s, want %s", addr, aliceAddr)
+	}
+	if status, msg := invoke(stub, []byte("transfer"), device.envelope(t, testBob+",30")); status != shim.OK {
+		t.Fatal("transfer with rotated key failed", msg)
+	}
+	expectQuery(t, stub, "70", "balanceOf", aliceAddr)
+	if status, _ := invoke(stub, []byte("transfer"), alice.envelope(t, testBob+",1")); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("old key transfer = %d", status)
+	}
+
+	status, payload := invoke(stub, []byte("getAccount"), []byte(aliceAddr))
+	account := wallet.Account{}
+	if status != shim.OK || json.Unmarshal([]byte(payload), &account) != nil || len(account.Keys) != 1 || account.Keys[0] != deviceAddr {
+		t.Errorf("getAccount = %d %s", status, payload)
+	}
+}
+
+func TestAccountRejectsLegacySignature(t *testing.T) {
+	stub := shim.NewMockStub(testChaincodeName, new(Chaincode))
+	alice, g1, mallory := newTestWallet(t), newTestWallet(t), newTestWallet(t)
+	aliceAddr, g1Addr, malloryAddr := alice.address(t, stub), g1.address(t, stub), mallory.address(t, stub)
+	setup := `{"guardians":["` + g1Addr + `"],"guardianthreshold":1,"recoverydelay":3600}`
+
+	// 기존 방식은 transdata 를 서명하지 않으므로 원장에 남은 전송 봉투의 transdata 를 바꿔서 키를 빼앗을 수 있다.
+	transferEnvelope := wallet.WalletMeta{}
+	if err := json.Unmarshal(alice.envelope(t, testBob+",10"), &transferEnvelope); err != nil {
+		t.Fatal(err)
+	}
+	transferEnvelope.Transdata = malloryAddr
+	hijack, _ := json.Marshal(transferEnvelope)
+	if status, _ := invoke(stub, []byte("rotateKey"), hijack); status != model.StatusCode(model.SignatureErrorType) {
+		t.Errorf("rotateKey with replayed legacy envelope = %d", status)
+	}
+
+	legacy := []struct {
+		function string
+		envelope []byte
+	}{
+		{"setupAccount", alice.sign(t, wallet.WalletMeta{Transjdata: setup}, wallet.SigContext{})},
+		{"approveRecovery", g1.envelope(t, aliceAddr+","+malloryAddr)},
+		{"cancelRecovery", alice.envelope(t, "")},
+	}
+	for _, tc := range legacy {
+		if status, _ := invoke(stub, []byte(tc.function), tc.envelope); status != model.StatusCode(model.SignatureErrorType) {
+			t.Errorf("legacy %s = %d", tc.function, status)
+		}
+	}
+	if status, _ := invoke(stub, []byte("getAccount"), []byte(aliceAddr)); status != model.StatusCode(model.AccountErrorType) {
+		t.Errorf("account created by legacy signature, getAccount = %d", status)
+	}
+
+	// canonical 서명으로 만든 계정도 기존 방식으로 복구를 승인하거나 취소할 수 없다.
+	if status, msg := alice.call(t, stub, "setupAccount", wallet.WalletMeta{Transjdata: setup}); status != shim.OK {
+		t.Fatal("setupAccount failed", msg)
+	}
+	for _, tc := range legacy[1:] {
+		if status, _ := invoke(stub, []byte(tc.function), tc.envelope); status != model.StatusCode(model.SignatureErrorType) {
+			t.Errorf("legacy %s after setup = %d", tc.function, status)
+		}
+	}
+	if addr := mallory.address(t, stub); addr != malloryAddr {
+		t.Errorf("mallory key resolves to %s", addr)
+	}
+}
+
+func TestGuardianRecovery(t *testing.T) {
+	stub := shim.NewMockStub(testChaincodeName, new(Chaincode))
+	alice, g1, g2, g3, recovered := newTestWallet(t), newTestWallet(t), newTestWallet(t), newTestWallet(t), newTestWallet(t)
+	aliceAddr, recoveredAddr := alice.address(t, stub), recovered.address(t, stub)
+	g1Addr, g2Addr := g1.address(t, stub), g2.address(t, stub)
+
+	setup := wallet.WalletMeta{Transjdata: `{"guardians":["` + g1Addr + `","` + g2Addr + `"],"guardianthreshold":2,"recoverydelay":3600}`}
+	if status, msg := alice.call(t, stub, "setupAccount", setup); status != shim.OK {
+		t.Fatal("setupAccount failed", msg)
+	}
+	short := wallet.WalletMeta{Transjdata: `{"guardians":["` + g1Addr + `"],"guardianthreshold":1,"recoverydelay":60}`}
+	if status, _ := alice.call(t, stub, "setupAccount", short); status != model.StatusCode(model.MandatoryPrameterErrorType) {
+		t.Errorf("short recovery delay = %d", status)
+	}
+
+	approve := wallet.WalletMeta{Transdata: aliceAddr + "," + recoveredAddr}
+	if status, _ := g3.call(t, stub, "approveRecovery", approve); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("approval by non guardian = %d", status)
+	}
+	for _, guardian := range []*testWallet{g1, g2} {
+		if status, msg := guardian.call(t, stub, "approveRecovery", approve); status != shim.OK {
+			t.Fatal("approveRecovery failed", msg)
+		}
+	}
+	if status, _ := g1.call(t, stub, "approveRecovery", approve); status != model.StatusCode(model.AccountErrorType) {
+		t.Errorf("repeated approval = %d", status)
+	}
+
+	// 대기 시간 전에는 실행할 수 없고, 키 소유자는 취소할 수 있다.
+	if status, _ := invoke(stub, []byte("executeRecovery"), []byte(aliceAddr)); status != model.StatusCode(model.AccountErrorType) {
+		t.Errorf("early executeRecovery = %d", status)
+	}
+	if status, msg := alice.call(t, stub, "cancelRecovery", wallet.WalletMeta{}); status != shim.OK {
+		t.Fatal("cancelRecovery failed", msg)
+	}
+	if status, _ := invoke(stub, []byte("executeRecovery"), []byte(aliceAddr)); status != model.StatusCode(model.AccountErrorType) {
+		t.Errorf("executeRecovery after cancel = %d", status)
+	}
+	if addr := alice.address(t, stub); addr != aliceAddr {
+		t.Errorf("account key address %s, want %s", addr, aliceAddr)
+	}
+}
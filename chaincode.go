@@ -6,10 +6,13 @@
 package main
 
 import (
-	"fmt"
+	"encoding/json"
+	"strconv"
+	"strings"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
 	"github.com/jinsan74/Erc20/wallet"
 )
 
@@ -18,9 +21,31 @@ type Chaincode struct {
 }
 
 // Init is called when the chaincode is instantiated by the blockchain network.
+// params - 토큰 정의 JSON (model.TokenInfo), 업그레이드 시에는 파라미터 없이 호출
 func (cc *Chaincode) Init(stub shim.ChaincodeStubInterface) sc.Response {
 
-	return shim.Success(nil)
+	_, params := stub.GetFunctionAndParameters()
+
+	info, err := getTokenInfo(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	// 이미 초기화된 토큰은 정의를 다시 받지 않는다.
+	if info != nil {
+		if len(params) > 0 {
+			return model.ErrorResponse(model.NewCustomError(model.InitializeErrorType, "token", "token is already initialized"))
+		}
+		if err := bootstrapOwnerRole(stub, info); err != nil {
+			return model.ErrorResponse(err)
+		}
+		return shim.Success(nil)
+	}
+
+	if len(params) != 1 {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "token", "incorrect number of arguments, expecting token definition"))
+	}
+	return cc.initToken(stub, params[0])
 }
 
 // Invoke is called as a result of an application request to run the chaincode.
@@ -31,6 +56,74 @@ func (cc *Chaincode) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
 	switch fcn {
 	case "walletTest":
 		return cc.WalletTest(stub, params)
+	case "verifySigner":
+		return cc.VerifySigner(stub, params)
+	case "getNonce":
+		return cc.GetNonce(stub, params)
+	case "validateAddress":
+		return cc.ValidateAddress(stub, params)
+	case "registerMultisig":
+		return cc.RegisterMultisig(stub, params)
+	case "getMultisig":
+		return cc.GetMultisig(stub, params)
+	case "setupAccount":
+		return cc.SetupAccount(stub, params)
+	case "rotateKey":
+		return cc.RotateKey(stub, params)
+	case "approveRecovery":
+		return cc.ApproveRecovery(stub, params)
+	case "cancelRecovery":
+		return cc.CancelRecovery(stub, params)
+	case "executeRecovery":
+		return cc.ExecuteRecovery(stub, params)
+	case "getAccount":
+		return cc.GetAccount(stub, params)
+	case "registerDelegate":
+		return cc.RegisterDelegate(stub, params)
+	case "revokeDelegate":
+		return cc.RevokeDelegate(stub, params)
+	case "getDelegate":
+		return cc.GetDelegate(stub, params)
+	case "grantRole":
+		return cc.GrantRole(stub, params)
+	case "revokeRole":
+		return cc.RevokeRole(stub, params)
+	case "hasRole":
+		return cc.HasRole(stub, params)
+	case "getRoleMembers":
+		return cc.GetRoleMembers(stub, params)
+	case "setTxTimeWindow":
+		return cc.SetTxTimeWindow(stub, params)
+	case "setSigConfig":
+		return cc.SetSigConfig(stub, params)
+	case "name":
+		return cc.Name(stub, params)
+	case "symbol":
+		return cc.Symbol(stub, params)
+	case "decimals":
+		return cc.Decimals(stub, params)
+	case "tokenInfo":
+		return cc.TokenInfo(stub, params)
+	case "totalSupply":
+		return cc.TotalSupply(stub, params)
+	case "balanceOf":
+		return cc.BalanceOf(stub, params)
+	case "balanceOfBatch":
+		return cc.BalanceOfBatch(stub, params)
+	case "allowance":
+		return cc.Allowance(stub, params)
+	case "transfer":
+		return cc.Transfer(stub, params)
+	case "approve":
+		return cc.Approve(stub, params)
+	case "transferFrom":
+		return cc.TransferFrom(stub, params)
+	case "mint":
+		return cc.Mint(stub, params)
+	case "burn":
+		return cc.Burn(stub, params)
+	case "migrateLegacyAddress":
+		return cc.MigrateLegacyAddress(stub, params)
 	default:
 		return sc.Response{Status: 404, Message: "404 Not Found", Payload: nil}
 	}
@@ -41,9 +134,181 @@ func (cc *Chaincode) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
 // params -
 func (cc *Chaincode) WalletTest(stub shim.ChaincodeStubInterface, args []string) sc.Response {
 
-	params := wallet.CallVaildWallet(stub)
-	fmt.Println("PARAM LEN:", len(params))
-	fmt.Println("WAddress:", params[0])
-	//fmt.Println("PARAM:", params[1])
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	return shim.Success([]byte(walletParams.Address))
+}
+
+// VerifySigner is 지갑 서명 확인, 서명자(wallet.Signer JSON)를 반환한다.
+// 다른 체인코드는 받은 지갑 파라미터를 그대로 전달해서 키 교체, 복구, nonce 와 서명 설정이 반영된 서명자를 얻는다.
+// 서명은 호출한 체인코드의 함수와 transdata 를 포함해야 하므로 canonical 서명과 지갑 자신의 키만 받는다.
+// params - 지갑 파라미터
+func (cc *Chaincode) VerifySigner(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	if err := wallet.RequireOwnKey(walletParams); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := wallet.RequireCanonicalSig(walletParams); err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	signerBytes, err := json.Marshal(wallet.Signer{Address: walletParams.Address, KeyAddress: walletParams.KeyAddress, LegacyAddress: walletParams.LegacyAddress})
+	if err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.MarshalErrorType, "Signer", err.Error()))
+	}
+	return shim.Success(signerBytes)
+}
+
+// GetNonce is 지갑주소가 다음에 서명할 nonce 조회
+// params - address
+func (cc *Chaincode) GetNonce(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 || args[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "getNonce", "incorrect number of arguments, expecting address"))
+	}
+
+	nonce, err := wallet.GetNonce(stub, args[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success([]byte(strconv.FormatUint(nonce, 10)))
+}
+
+// AddressInfo is validateAddress 조회 결과
+type AddressInfo struct {
+	Address string `json:"address"` // 매핑된 기존 형식 주소이면 새 주소
+	Version uint8  `json:"version"`
+	Type    string `json:"type"`
+}
+
+// ValidateAddress is 받는 지갑주소로 쓸 수 있는지 확인
+// 잘못된 주소이면 AddressErrorType 오류를 반환한다.
+// params - address
+func (cc *Chaincode) ValidateAddress(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 || args[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "validateAddress", "incorrect number of arguments, expecting address"))
+	}
+
+	address, err := resolveRecipient(stub, args[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	version, _, err := wallet.DecodeAddress(address)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	infoBytes, err := json.Marshal(AddressInfo{Address: address, Version: version, Type: wallet.AddressType(version)})
+	if err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.MarshalErrorType, "AddressInfo", err.Error()))
+	}
+	return shim.Success(infoBytes)
+}
+
+// RegisterMultisig is multisig 지갑 정책 등록, multisig 지갑주소를 반환한다.
+// 주소가 정책으로 정해지므로 누구나 등록할 수 있다.
+// params - 정책 JSON (wallet.MultisigPolicy)
+func (cc *Chaincode) RegisterMultisig(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 || args[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "registerMultisig", "incorrect number of arguments, expecting policy"))
+	}
+
+	policy := wallet.MultisigPolicy{}
+	decoder := json.NewDecoder(strings.NewReader(args[0]))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.UnMarshalErrorType, "MultisigPolicy", err.Error()))
+	}
+
+	address, err := wallet.RegisterMultisig(stub, policy)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success([]byte(address))
+}
+
+// GetMultisig is multisig 지갑 정책 조회 (JSON)
+// params - address
+func (cc *Chaincode) GetMultisig(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 || args[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "getMultisig", "incorrect number of arguments, expecting address"))
+	}
+
+	policy, err := wallet.GetMultisig(stub, args[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if policy == nil {
+		return model.ErrorResponse(model.NewCustomError(model.AddressErrorType, args[0], "multisig wallet is not registered"))
+	}
+	policyBytes, err := json.Marshal(policy)
+	if err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.MarshalErrorType, "MultisigPolicy", err.Error()))
+	}
+	return shim.Success(policyBytes)
+}
+
+// SetTxTimeWindow is 지갑 트랜잭션 txtime 허용 범위 변경 (owner 역할의 canonical 서명만 가능)
+// transjdata - model.TxTimeConfig JSON
+func (cc *Chaincode) SetTxTimeWindow(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	if walletParams.Jdata == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "setTxTimeWindow", "transjdata must be tx time config"))
+	}
+
+	if err := requireOwnerSig(stub, walletParams); err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	cfg := model.TxTimeConfig{}
+	decoder := json.NewDecoder(strings.NewReader(walletParams.Jdata))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.UnMarshalErrorType, "TxTimeConfig", err.Error()))
+	}
+	if err := wallet.PutTxTimeConfig(stub, cfg); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// SetSigConfig is 지갑 서명 방식 설정 변경 (owner 역할의 canonical 서명만 가능)
+// transjdata - model.SigConfig JSON
+func (cc *Chaincode) SetSigConfig(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	if walletParams.Jdata == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "setSigConfig", "transjdata must be signature config"))
+	}
+
+	if err := requireOwnerSig(stub, walletParams); err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	cfg := model.SigConfig{}
+	decoder := json.NewDecoder(strings.NewReader(walletParams.Jdata))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.UnMarshalErrorType, "SigConfig", err.Error()))
+	}
+	if err := wallet.PutSigConfig(stub, cfg); err != nil {
+		return model.ErrorResponse(err)
+	}
 	return shim.Success(nil)
 }
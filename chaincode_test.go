@@ -5,30 +5,122 @@
 package main
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
 )
 
 func TestInit(t *testing.T) {
 	cc := new(Chaincode)
 	stub := shim.NewMockStub("chaincode", cc)
-	res := stub.MockInit("1", [][]byte{[]byte("initFunc")})
+	res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(`{"name":"Sejong Token","symbol":"SJT","decimals":8,"initialsupply":1000,"owner":"BV8oLGiPd8qTGsEqqJ7b9V7X29VuQcea2C","legacyowner":true}`)})
 	if res.Status != shim.OK {
 		t.Error("Init failed", res.Status, res.Message)
 	}
+
+	// 업그레이드 시 파라미터 없는 Init 은 허용, 재정의는 거부
+	res = stub.MockInit("2", [][]byte{[]byte("init")})
+	if res.Status != shim.OK {
+		t.Error("Upgrade Init failed", res.Status, res.Message)
+	}
+	res = stub.MockInit("3", [][]byte{[]byte("init"), []byte(`{"name":"Other","symbol":"OTH","initialsupply":1,"owner":"BV8oLGiPd8qTGsEqqJ7b9V7X29VuQcea2C","legacyowner":true}`)})
+	if res.Status == shim.OK {
+		t.Error("Re-initialization succeeded")
+	}
+}
+
+func TestInitRejectsInvalidDefinition(t *testing.T) {
+	for _, def := range []string{
+		``,
+		`{"name":"Sejong Token","symbol":"SJT","initialsupply":1000}`,
+		`{"name":"Sejong Token","symbol":"SJT","initialsupply":1000,"owner":"A","cap":999}`,
+		`{"name":"Sejong Token","symbol":"SJT","decimals":19,"owner":"A"}`,
+		`{"name":"Sejong Token","symbol":"SJT","owner":"A","unknown":1}`,
+		`{"name":"Sejong Token","symbol":"SJT","initialsupply":1000,"owner":"owner"}`,
+		// 체크섬이 틀린 주소 (testBob 의 마지막 문자를 바꿨다)
+		`{"name":"Sejong Token","symbol":"SJT","initialsupply":1000,"owner":"tp2kD5fcscYztAfUABjNM2iCduAzNZdsBh"}`,
+		// 기존 형식 주소는 legacyowner 로 명시해야 한다.
+		`{"name":"Sejong Token","symbol":"SJT","initialsupply":1000,"owner":"BV8oLGiPd8qTGsEqqJ7b9V7X29VuQcea2C"}`,
+		`{"name":"Sejong Token","symbol":"SJT","initialsupply":1000,"owner":"BV8oLGiPd8qTGsEqqJ7b9V7X29VuQcea0C","legacyowner":true}`,
+	} {
+		stub := shim.NewMockStub("chaincode", new(Chaincode))
+		args := [][]byte{[]byte("init")}
+		if def != "" {
+			args = append(args, []byte(def))
+		}
+		if res := stub.MockInit("1", args); res.Status == shim.OK {
+			t.Errorf("Init(%s) succeeded", def)
+		}
+	}
 }
 
 func TestWalletTest(t *testing.T) {
 	cc := new(Chaincode)
 	stub := shim.NewMockStub("chaincode", cc)
-	// res := stub.MockInit("1", [][]byte{[]byte("init"), []byte("JK0001"), []byte("BV8oLGiPd8qTGsEqqJ7b9V7X29VuQcea2C")})
-	// if res.Status != shim.OK {
-	// 	t.Error("Init failed", res.Status, res.Message)
-	// }
-	res := stub.MockInvoke("1", [][]byte{[]byte("walletTest"), []byte("{\"publickey\":\"00B413D9FAD1FC5B50CB93B9B7C554CE5B3A449115AA361B24B46D9156E0512E25:00E9FC5AAAF269E7EB0BD5003DC3C9F7FF159522FE62E2F23E7F717481809044A5\",\"txtime\":\"1609128127\",\"transdata\":\"1,2\",\"sigmsg\":\"3045022100A06221FFA0C44A0A12051A1C8694D4BA475C3D121D069D6EA6AF0F7576426E9A02206DECA4CCB32A0A86913281DE0C05A27DAF16E82F894E6D34E6E1488CE4AE5B8F\"}")})
+	// 고정된 서명 봉투(2020-12-28)를 쓰므로 txtime 검사를 끈다.
+	res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(`{"name":"Sejong Token","symbol":"SJT","initialsupply":1000,"owner":"BV8oLGiPd8qTGsEqqJ7b9V7X29VuQcea2C","legacyowner":true,"txtime":{"pastsec":10,"futuresec":10,"disabled":true}}`)})
+	if res.Status != shim.OK {
+		t.Error("Init failed", res.Status, res.Message)
+	}
+	res = stub.MockInvoke("1", [][]byte{[]byte("walletTest"), []byte("{\"publickey\":\"00B413D9FAD1FC5B50CB93B9B7C554CE5B3A449115AA361B24B46D9156E0512E25:00E9FC5AAAF269E7EB0BD5003DC3C9F7FF159522FE62E2F23E7F717481809044A5\",\"txtime\":\"1609128127\",\"transdata\":\"1,2\",\"sigmsg\":\"3045022100A06221FFA0C44A0A12051A1C8694D4BA475C3D121D069D6EA6AF0F7576426E9A02206DECA4CCB32A0A86913281DE0C05A27DAF16E82F894E6D34E6E1488CE4AE5B8F\"}")})
 	if res.Status != shim.OK {
 		t.Error("Invoke failed", res.Status, res.Message)
 	}
 
 }
+
+func TestWalletTestReportsErrorType(t *testing.T) {
+	cc := new(Chaincode)
+	stub := shim.NewMockStub("chaincode", cc)
+
+	// 필수 파라미터가 빠진 봉투
+	res := stub.MockInvoke("1", [][]byte{[]byte("walletTest"), []byte("{\"publickey\":\"00B413D9FAD1FC5B50CB93B9B7C554CE5B3A449115AA361B24B46D9156E0512E25:00E9FC5AAAF269E7EB0BD5003DC3C9F7FF159522FE62E2F23E7F717481809044A5\"}")})
+	if res.Status != model.StatusCode(model.MandatoryPrameterErrorType) {
+		t.Error("malformed envelope", res.Status, res.Message)
+	}
+
+	customErr := model.CustomError{}
+	if err := json.Unmarshal(res.Payload, &customErr); err != nil || customErr.ErrorType != model.MandatoryPrameterErrorType {
+		t.Error("error payload", string(res.Payload), err)
+	}
+}
+
+func TestWalletTestRejectsMalformedEnvelope(t *testing.T) {
+	const pub = "00B413D9FAD1FC5B50CB93B9B7C554CE5B3A449115AA361B24B46D9156E0512E25:00E9FC5AAAF269E7EB0BD5003DC3C9F7FF159522FE62E2F23E7F717481809044A5"
+	const sig = "3045022100A06221FFA0C44A0A12051A1C8694D4BA475C3D121D069D6EA6AF0F7576426E9A02206DECA4CCB32A0A86913281DE0C05A27DAF16E82F894E6D34E6E1488CE4AE5B8F"
+
+	cases := []struct {
+		name      string
+		args      []string
+		errorType string
+	}{
+		{"no envelope", nil, model.MandatoryPrameterErrorType},
+		{"extra argument", []string{`{}`, `{}`}, model.MandatoryPrameterErrorType},
+		{"not json", []string{`publickey`}, model.UnMarshalErrorType},
+		{"unknown field", []string{`{"publickey":"` + pub + `","txtime":"1609128127","sigmsg":"` + sig + `","amount":1}`}, model.UnMarshalErrorType},
+		{"trailing data", []string{`{"publickey":"` + pub + `","txtime":"1609128127","sigmsg":"` + sig + `"}}`}, model.UnMarshalErrorType},
+		{"key without Y", []string{`{"publickey":"00B413D9","txtime":"1609128127","sigmsg":"` + sig + `"}`}, model.PublicKeyErrorType},
+		{"key with three parts", []string{`{"publickey":"` + pub + `:00","txtime":"1609128127","sigmsg":"` + sig + `"}`}, model.PublicKeyErrorType},
+		{"key not hex", []string{`{"publickey":"ZZ:00","txtime":"1609128127","sigmsg":"` + sig + `"}`}, model.HexDecodeErrorType},
+		{"key off curve", []string{`{"publickey":"01:02","txtime":"1609128127","sigmsg":"` + sig + `"}`}, model.CurvePointErrorType},
+		{"sigmsg not hex", []string{`{"publickey":"` + pub + `","txtime":"1609128127","sigmsg":"30XY"}`}, model.HexDecodeErrorType},
+	}
+
+	stub := shim.NewMockStub("chaincode", new(Chaincode))
+	res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(`{"name":"Sejong Token","symbol":"SJT","owner":"tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg","txtime":{"pastsec":0,"futuresec":0,"disabled":true}}`)})
+	if res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+	for _, tc := range cases {
+		args := [][]byte{[]byte("walletTest")}
+		for _, arg := range tc.args {
+			args = append(args, []byte(arg))
+		}
+		res := stub.MockInvoke("1", args)
+		if res.Status != model.StatusCode(tc.errorType) {
+			t.Errorf("%s: got %d %s, want %s", tc.name, res.Status, res.Message, tc.errorType)
+		}
+	}
+}
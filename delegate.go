@@ -0,0 +1,90 @@
+/*
+ * SejongTelecom 코어기술개발팀
+ * @author JinSan
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+// RegisterDelegate is 지갑형 위임 키 등록 (지갑 키로 서명)
+// 위임 키는 expiry 전까지 functions 에 있는 함수를 지갑으로 호출할 수 있고, spendlimit 까지 토큰을 보낼 수 있다.
+// expiry 는 클라이언트가 정한 proposal timestamp 와 비교하는 권고 값이므로 확실히 막으려면 revokeDelegate 를 호출한다.
+// transjdata - wallet.DelegateConfig
+func (cc *Chaincode) RegisterDelegate(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+
+	cfg := wallet.DelegateConfig{}
+	decoder := json.NewDecoder(strings.NewReader(walletParams.Jdata))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.UnMarshalErrorType, "DelegateConfig", err.Error()))
+	}
+
+	now, err := txSeconds(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	delegation, err := wallet.RegisterDelegate(stub, walletParams, cfg, now)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return delegationResponse(delegation)
+}
+
+// RevokeDelegate is 지갑형 위임 키 삭제 (지갑 키 또는 위임 키로 서명)
+// transdata - delegateaddress
+func (cc *Chaincode) RevokeDelegate(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	params := walletParams.Params
+	if len(params) != 1 || params[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "revokeDelegate", "transdata must be delegateaddress"))
+	}
+
+	if err := wallet.RevokeDelegate(stub, walletParams, params[0]); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// GetDelegate is 위임 키 조회 (JSON)
+// params - delegateaddress
+func (cc *Chaincode) GetDelegate(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 || args[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "getDelegate", "incorrect number of arguments, expecting delegate address"))
+	}
+
+	delegation, err := wallet.GetDelegation(stub, args[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if delegation == nil {
+		return model.ErrorResponse(model.NewCustomError(model.DelegateErrorType, args[0], "delegate key is not registered"))
+	}
+	return delegationResponse(delegation)
+}
+
+func delegationResponse(delegation *wallet.Delegation) sc.Response {
+	delegationBytes, err := json.Marshal(delegation)
+	if err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.MarshalErrorType, "Delegation", err.Error()))
+	}
+	return shim.Success(delegationBytes)
+}
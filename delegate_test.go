@@ -0,0 +1,74 @@
+/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+func TestDelegateKey(t *testing.T) {
+	stub := shim.NewMockStub(testChaincodeName, new(Chaincode))
+	alice, session := newTestWallet(t), newTestWallet(t)
+	aliceAddr, sessionAddr := alice.address(t, stub), session.address(t, stub)
+	seedBalance(t, stub, aliceAddr, 100)
+
+	register := func(cfg string) (int32, string) {
+		return alice.call(t, stub, "registerDelegate", wallet.WalletMeta{Transjdata: cfg})
+	}
+	expiry := strconv.FormatInt(time.Now().Unix()+3600, 10)
+	legacy := alice.sign(t, wallet.WalletMeta{Transjdata: `{"delegate":"` + sessionAddr + `","expiry":` + expiry + `,"functions":["transfer"],"spendlimit":50}`}, wallet.SigContext{})
+	if status, _ := invoke(stub, []byte("registerDelegate"), legacy); status != model.StatusCode(model.SignatureErrorType) {
+		t.Errorf("legacy registration = %d", status)
+	}
+	if status, _ := register(`{"delegate":"` + sessionAddr + `","expiry":1,"functions":["transfer"],"spendlimit":50}`); status != model.StatusCode(model.MandatoryPrameterErrorType) {
+		t.Errorf("expired registration = %d", status)
+	}
+	if status, msg := register(`{"delegate":"` + sessionAddr + `","expiry":` + expiry + `,"functions":["transfer"],"spendlimit":50}`); status != shim.OK {
+		t.Fatal("registerDelegate failed", msg)
+	}
+
+	// 위임 키의 서명은 alice 의 지갑으로 처리된다.
+	if status, _ := invoke(stub, []byte("walletTest"), session.envelope(t, "")); status != model.StatusCode(model.DelegateErrorType) {
+		t.Errorf("walletTest outside whitelist = %d", status)
+	}
+	if status, msg := invoke(stub, []byte("transfer"), session.envelope(t, testBob+",30")); status != shim.OK {
+		t.Fatal("delegate transfer failed", msg)
+	}
+	expectQuery(t, stub, "70", "balanceOf", aliceAddr)
+	if status, _ := invoke(stub, []byte("transfer"), session.envelope(t, testBob+",30")); status != model.StatusCode(model.DelegateErrorType) {
+		t.Errorf("transfer over spend limit = %d", status)
+	}
+	if status, _ := invoke(stub, []byte("approve"), session.envelope(t, testBob+",30")); status != model.StatusCode(model.DelegateErrorType) {
+		t.Errorf("approve outside whitelist = %d", status)
+	}
+
+	// 다른 지갑은 같은 키를 위임 키로 가져갈 수 없다.
+	mallory := newTestWallet(t)
+	hijack := wallet.WalletMeta{Transjdata: `{"delegate":"` + sessionAddr + `","expiry":` + expiry + `,"functions":["transfer"],"spendlimit":100}`}
+	if status, _ := mallory.call(t, stub, "registerDelegate", hijack); status != model.StatusCode(model.DelegateErrorType) {
+		t.Errorf("registration by another wallet = %d", status)
+	}
+
+	// 삭제하면 다음 트랜잭션부터 위임 키는 자기 지갑으로만 서명한다.
+	if status, _ := invoke(stub, []byte("revokeDelegate"), alice.envelope(t, sessionAddr)); status != model.StatusCode(model.SignatureErrorType) {
+		t.Errorf("legacy revoke = %d", status)
+	}
+	if status, msg := alice.call(t, stub, "revokeDelegate", wallet.WalletMeta{Transdata: sessionAddr}); status != shim.OK {
+		t.Fatal("revokeDelegate failed", msg)
+	}
+	if status, _ := invoke(stub, []byte("transfer"), session.envelope(t, testBob+",10")); status != model.StatusCode(model.InsufficientBalanceErrorType) {
+		t.Errorf("transfer after revoke = %d", status)
+	}
+	expectQuery(t, stub, "70", "balanceOf", aliceAddr)
+	if status, _ := invoke(stub, []byte("getDelegate"), []byte(sessionAddr)); status != model.StatusCode(model.DelegateErrorType) {
+		t.Errorf("getDelegate after revoke = %d", status)
+	}
+}
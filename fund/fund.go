@@ -0,0 +1,393 @@
+/*
+ * SejongTelecom 코어기술개발팀
+ * @author JinSan
+ */
+
+// 펀드 레지스트리 체인코드
+// 펀드마다 대표 관리자(owner)와 관리자(admin, operator)를 등록한다. 다른 체인코드는 utils.GetFundAdmin, utils.HasFundRole 로 조회한다.
+// 지갑 서명은 토큰 체인코드의 verifySigner 로 확인하므로 키 교체, 복구, nonce 와 서명 설정이 토큰과 같게 적용된다.
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/rbac"
+	"github.com/jinsan74/Erc20/tokenclient"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+const (
+	fundKeyType       = "fund"
+	tokenChaincodeKey = "tokenChaincode" // 서명자와 역할을 확인할 토큰 체인코드 이름
+)
+
+// Chaincode is the definition of the chaincode structure.
+type Chaincode struct {
+}
+
+// Init is called when the chaincode is instantiated by the blockchain network.
+// params - 토큰 체인코드 이름, 업그레이드 시에는 생략하면 설정된 이름을 그대로 쓴다
+func (cc *Chaincode) Init(stub shim.ChaincodeStubInterface) sc.Response {
+
+	_, params := stub.GetFunctionAndParameters()
+	if len(params) > 1 || (len(params) == 1 && params[0] == "") {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "fund", "incorrect number of arguments, expecting token chaincode name"))
+	}
+	if len(params) == 0 {
+		if _, err := getTokenChaincode(stub); err != nil {
+			return model.ErrorResponse(err)
+		}
+		return shim.Success(nil)
+	}
+	if err := stub.PutState(tokenChaincodeKey, []byte(params[0])); err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.PutStateErrorType, tokenChaincodeKey, err.Error()))
+	}
+	return shim.Success(nil)
+}
+
+// Invoke is called as a result of an application request to run the chaincode.
+func (cc *Chaincode) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
+
+	fcn, params := stub.GetFunctionAndParameters()
+
+	switch fcn {
+	case "registerFund":
+		return cc.RegisterFund(stub, params)
+	case "setFundRole":
+		return cc.SetFundRole(stub, params)
+	case "transferFundAdmin":
+		return cc.TransferFundAdmin(stub, params)
+	case "acceptFundAdmin":
+		return cc.AcceptFundAdmin(stub, params)
+	case "fundAddressSearch":
+		return cc.FundAddressSearch(stub, params)
+	case "hasFundRole":
+		return cc.HasFundRole(stub, params)
+	case "getFund":
+		return cc.GetFund(stub, params)
+	default:
+		return sc.Response{Status: 404, Message: "404 Not Found", Payload: nil}
+	}
+}
+
+// RegisterFund is 지갑형 펀드 등록 (서명자가 대표 관리자, 토큰 체인코드의 fundadmin 또는 owner 역할만 가능)
+// transdata - fundid
+func (cc *Chaincode) RegisterFund(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	client, signer, params, err := walletSigner(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if len(params) != 1 || params[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "registerFund", "transdata must be fundid"))
+	}
+	if err := requireTokenRole(client, signer, rbac.RoleFundAdmin, rbac.RoleOwner); err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	fund, err := getFund(stub, params[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if fund != nil {
+		return model.ErrorResponse(model.NewCustomError(model.FundErrorType, params[0], "fund is already registered"))
+	}
+
+	fund = &model.Fund{FundID: params[0], Admin: signer.Address}
+	if err := putFund(stub, fund); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return fundResponse(fund)
+}
+
+// SetFundRole is 지갑형 펀드 관리자 지정 및 해제
+// owner 는 admin, operator 를 지정할 수 있고 admin 은 operator 만 지정할 수 있다. role 이 비어 있으면 해제한다.
+// transdata - fundid, address, role
+func (cc *Chaincode) SetFundRole(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	_, signer, params, err := walletSigner(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if len(params) != 3 || params[0] == "" || params[1] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "setFundRole", "transdata must be fundid,address,role"))
+	}
+	address, role := params[1], params[2]
+	if role != "" && !model.ValidFundRole(role) {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "role", "role must be "+model.FundRoleAdmin+", "+model.FundRoleOperator+" or empty"))
+	}
+	if err := wallet.ValidateAddress(address); err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	fund, err := requireFund(stub, params[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if address == fund.Admin {
+		return model.ErrorResponse(model.NewCustomError(model.FundErrorType, address, "owner role changes only by transferFundAdmin"))
+	}
+
+	// 지정하거나 해제하는 역할보다 높은 권한이 있어야 한다.
+	current := fund.FundRole(address)
+	signerRole := model.FundRoleAdmin
+	if role == model.FundRoleAdmin || current == model.FundRoleAdmin {
+		signerRole = model.FundRoleOwner
+	}
+	if !fund.HasFundRole(signer.Address, signerRole) {
+		return model.ErrorResponse(model.NewCustomError(model.PermissionErrorType, signer.Address, "requires fund role "+signerRole))
+	}
+
+	members := make([]model.FundMember, 0, len(fund.Members)+1)
+	for _, member := range fund.Members {
+		if member.Address != address {
+			members = append(members, member)
+		}
+	}
+	if role != "" {
+		members = append(members, model.FundMember{Address: address, Role: role})
+	}
+	fund.Members = members
+
+	if err := putFund(stub, fund); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return fundResponse(fund)
+}
+
+// TransferFundAdmin is 지갑형 대표 관리자 이전 요청 (대표 관리자가 서명)
+// 새 대표 관리자가 acceptFundAdmin 을 서명해야 이전된다. 다시 요청하면 이전 요청을 대신한다.
+// transdata - fundid, newadmin
+func (cc *Chaincode) TransferFundAdmin(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	_, signer, params, err := walletSigner(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if len(params) != 2 || params[0] == "" || params[1] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "transferFundAdmin", "transdata must be fundid,newadmin"))
+	}
+	if err := wallet.ValidateAddress(params[1]); err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	fund, err := requireFund(stub, params[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if signer.Address != fund.Admin {
+		return model.ErrorResponse(model.NewCustomError(model.PermissionErrorType, signer.Address, "requires fund role "+model.FundRoleOwner))
+	}
+	if params[1] == fund.Admin {
+		return model.ErrorResponse(model.NewCustomError(model.FundErrorType, params[1], "address is already the fund admin"))
+	}
+
+	fund.PendingAdmin = params[1]
+	if err := putFund(stub, fund); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return fundResponse(fund)
+}
+
+// AcceptFundAdmin is 지갑형 대표 관리자 이전 수락 (새 대표 관리자가 서명)
+// 이전 대표 관리자는 관리자에서 제외된다.
+// transdata - fundid
+func (cc *Chaincode) AcceptFundAdmin(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	_, signer, params, err := walletSigner(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if len(params) != 1 || params[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "acceptFundAdmin", "transdata must be fundid"))
+	}
+
+	fund, err := requireFund(stub, params[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if fund.PendingAdmin == "" || signer.Address != fund.PendingAdmin {
+		return model.ErrorResponse(model.NewCustomError(model.PermissionErrorType, signer.Address, "address is not the pending fund admin"))
+	}
+
+	members := make([]model.FundMember, 0, len(fund.Members))
+	for _, member := range fund.Members {
+		if member.Address != fund.PendingAdmin {
+			members = append(members, member)
+		}
+	}
+	fund.Members = members
+	fund.Admin, fund.PendingAdmin = fund.PendingAdmin, ""
+
+	if err := putFund(stub, fund); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return fundResponse(fund)
+}
+
+// FundAddressSearch is 펀드 대표 관리자 지갑주소 조회
+// 등록되지 않은 펀드이면 오류 응답을 돌려준다.
+// params - fundid
+func (cc *Chaincode) FundAddressSearch(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 || args[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "fundAddressSearch", "incorrect number of arguments, expecting fundid"))
+	}
+	fund, err := requireFund(stub, args[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success([]byte(fund.Admin))
+}
+
+// HasFundRole is 펀드 역할 확인 ("true" 또는 "false")
+// params - fundid, address, role
+func (cc *Chaincode) HasFundRole(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 3 || args[0] == "" || args[1] == "" || args[2] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "hasFundRole", "incorrect number of arguments, expecting fundid,address,role"))
+	}
+	if args[2] != model.FundRoleOwner && !model.ValidFundRole(args[2]) {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "role", "unknown fund role "+args[2]))
+	}
+	fund, err := requireFund(stub, args[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if fund.HasFundRole(args[1], args[2]) {
+		return shim.Success([]byte("true"))
+	}
+	return shim.Success([]byte("false"))
+}
+
+// GetFund is 펀드 레지스트리 항목 조회 (JSON)
+// params - fundid
+func (cc *Chaincode) GetFund(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 || args[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "getFund", "incorrect number of arguments, expecting fundid"))
+	}
+	fund, err := requireFund(stub, args[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return fundResponse(fund)
+}
+
+// walletSigner 는 지갑 파라미터를 바꾸지 않고 토큰 체인코드에 전달해서 서명자를 확인하고, transdata 의 위치 파라미터를 반환한다.
+func walletSigner(stub shim.ChaincodeStubInterface) (*tokenclient.Client, *wallet.Signer, []string, error) {
+	tokenChaincode, err := getTokenChaincode(stub)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	client := tokenclient.New(stub, tokenChaincode)
+	signer, err := client.VerifySigner()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	_, orgParam := stub.GetFunctionAndParameters()
+	walletMeta, err := wallet.ParseWalletMeta(orgParam[0])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var params []string
+	if walletMeta.Transdata != "" {
+		params = strings.Split(walletMeta.Transdata, ",")
+	}
+	return client, signer, params, nil
+}
+
+// requireTokenRole 은 서명자에게 토큰 체인코드의 roles 중 하나도 없으면 오류를 반환한다.
+// 기존 형식 주소로 받은 역할도 같은 키의 서명이면 인정한다.
+func requireTokenRole(client *tokenclient.Client, signer *wallet.Signer, roles ...string) error {
+	for _, role := range roles {
+		for _, address := range []string{signer.Address, signer.LegacyAddress} {
+			if address == "" {
+				continue
+			}
+			ok, err := client.HasRole(address, role)
+			if err != nil || ok {
+				return err
+			}
+		}
+	}
+	return model.NewCustomError(model.PermissionErrorType, signer.Address, "requires role "+strings.Join(roles, " or "))
+}
+
+// getTokenChaincode 는 Init 에서 설정한 토큰 체인코드 이름을 반환한다.
+func getTokenChaincode(stub shim.ChaincodeStubInterface) (string, error) {
+	name, err := stub.GetState(tokenChaincodeKey)
+	if err != nil {
+		return "", model.NewCustomError(model.GetStateErrorType, tokenChaincodeKey, err.Error())
+	}
+	if len(name) == 0 {
+		return "", model.NewCustomError(model.InitializeErrorType, tokenChaincodeKey, "token chaincode is not set")
+	}
+	return string(name), nil
+}
+
+func fundResponse(fund *model.Fund) sc.Response {
+	fundBytes, err := json.Marshal(fund)
+	if err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.MarshalErrorType, "Fund", err.Error()))
+	}
+	return shim.Success(fundBytes)
+}
+
+func requireFund(stub shim.ChaincodeStubInterface, fundID string) (*model.Fund, error) {
+	fund, err := getFund(stub, fundID)
+	if err != nil {
+		return nil, err
+	}
+	if fund == nil {
+		return nil, model.NewCustomError(model.FundErrorType, fundID, "fund is not registered")
+	}
+	return fund, nil
+}
+
+func getFund(stub shim.ChaincodeStubInterface, fundID string) (*model.Fund, error) {
+	key, err := fundKey(stub, fundID)
+	if err != nil {
+		return nil, err
+	}
+	fundBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, model.NewCustomError(model.GetStateErrorType, key, err.Error())
+	}
+	if fundBytes == nil {
+		return nil, nil
+	}
+	fund := model.Fund{}
+	if err := json.Unmarshal(fundBytes, &fund); err != nil {
+		return nil, model.NewCustomError(model.UnMarshalErrorType, key, err.Error())
+	}
+	return &fund, nil
+}
+
+func putFund(stub shim.ChaincodeStubInterface, fund *model.Fund) error {
+	key, err := fundKey(stub, fund.FundID)
+	if err != nil {
+		return err
+	}
+	fundBytes, err := json.Marshal(fund)
+	if err != nil {
+		return model.NewCustomError(model.MarshalErrorType, "Fund", err.Error())
+	}
+	if err := stub.PutState(key, fundBytes); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	return nil
+}
+
+func fundKey(stub shim.ChaincodeStubInterface, fundID string) (string, error) {
+	key, err := stub.CreateCompositeKey(fundKeyType, []string{fundID})
+	if err != nil {
+		return "", model.NewCustomError(model.CreateCompositeKeyErrorType, fundKeyType, err.Error())
+	}
+	return key, nil
+}
@@ -0,0 +1,250 @@
+/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/rbac"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+const testTokenChaincode = "erc20"
+
+// testWallet 은 P-256 지갑 (서명은 tokenChaincode 가 확인하지 않는다)
+type testWallet struct {
+	pub     string
+	address string
+}
+
+func newTestWallet(t *testing.T) *testWallet {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := fmt.Sprintf("%064X:%064X", key.X, key.Y)
+	address, err := wallet.AddressFromPublicKey(pub, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testWallet{pub: pub, address: address}
+}
+
+// envelope 는 sigver 3 지갑 파라미터를 만든다.
+func (w *testWallet) envelope(t *testing.T, transdata string) []byte {
+	return w.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerSHA256, Transdata: transdata})
+}
+
+func (w *testWallet) sign(t *testing.T, meta wallet.WalletMeta) []byte {
+	meta.Publickey = w.pub
+	meta.Txtime = strconv.FormatInt(time.Now().Unix(), 10)
+	meta.Sigmsg = "00"
+	metaBytes, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metaBytes
+}
+
+// tokenChaincode 는 토큰 체인코드의 verifySigner, hasRole 을 흉내 낸다.
+// 서명 검증은 토큰 체인코드 테스트에서 확인하므로 여기서는 봉투의 공개키와 계정 매핑으로 서명자를 정한다.
+type tokenChaincode struct {
+	accounts map[string]string // 서명 키 지갑주소 -> 계정 주소
+	roles    map[string]bool   // "address,role"
+}
+
+func (tc *tokenChaincode) Init(stub shim.ChaincodeStubInterface) sc.Response {
+	return shim.Success(nil)
+}
+
+func (tc *tokenChaincode) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
+	fcn, params := stub.GetFunctionAndParameters()
+	switch fcn {
+	case "verifySigner":
+		meta, err := wallet.ParseWalletMeta(params[0])
+		if err != nil {
+			return model.ErrorResponse(err)
+		}
+		if meta.Sigver != wallet.SigVerCanonical && meta.Sigver != wallet.SigVerSHA256 {
+			return model.ErrorResponse(model.NewCustomError(model.SignatureErrorType, model.SigReasonSigver, "requires canonical signature"))
+		}
+		key, err := wallet.AddressFromPublicKey(meta.Publickey, meta.Curve, meta.Keytype)
+		if err != nil {
+			return model.ErrorResponse(err)
+		}
+		signer := wallet.Signer{Address: key, KeyAddress: key}
+		if account, ok := tc.accounts[key]; ok {
+			signer.Address = account
+		}
+		signerBytes, _ := json.Marshal(signer)
+		return shim.Success(signerBytes)
+	case "hasRole":
+		return shim.Success([]byte(strconv.FormatBool(tc.roles[params[0]+","+params[1]])))
+	}
+	return sc.Response{Status: 404, Message: "404 Not Found"}
+}
+
+// newFundStub 은 token 을 토큰 체인코드로 설정한 펀드 체인코드 stub 을 만든다.
+func newFundStub(t *testing.T, token *tokenChaincode) *shim.MockStub {
+	stub := shim.NewMockStub("fund", new(Chaincode))
+	stub.ChannelID = "mychannel"
+	tokenStub := shim.NewMockStub(testTokenChaincode, token)
+	stub.MockPeerChaincode(testTokenChaincode+"/mychannel", tokenStub)
+	if res := stub.MockInit("init", [][]byte{[]byte("init"), []byte(testTokenChaincode)}); res.Status != shim.OK {
+		t.Fatal("fund Init failed", res.Message)
+	}
+	return stub
+}
+
+func invoke(stub *shim.MockStub, args ...string) (int32, string) {
+	bargs := make([][]byte, len(args))
+	for i, arg := range args {
+		bargs[i] = []byte(arg)
+	}
+	res := stub.MockInvoke("tx", bargs)
+	if res.Status != shim.OK {
+		return res.Status, res.Message
+	}
+	return res.Status, string(res.Payload)
+}
+
+func TestFundRegistry(t *testing.T) {
+	owner, admin, operator, newOwner := newTestWallet(t), newTestWallet(t), newTestWallet(t), newTestWallet(t)
+	stub := newFundStub(t, &tokenChaincode{roles: map[string]bool{owner.address + "," + rbac.RoleFundAdmin: true}})
+
+	if status, msg := invoke(stub, "registerFund", string(owner.envelope(t, "F1"))); status != shim.OK {
+		t.Fatal("registerFund failed", msg)
+	}
+	if status, _ := invoke(stub, "registerFund", string(owner.envelope(t, "F1"))); status != model.StatusCode(model.FundErrorType) {
+		t.Errorf("duplicate registerFund = %d", status)
+	}
+	if status, admin := invoke(stub, "fundAddressSearch", "F1"); status != shim.OK || admin != owner.address {
+		t.Errorf("fundAddressSearch = %d %s", status, admin)
+	}
+	if status, _ := invoke(stub, "fundAddressSearch", "F2"); status != model.StatusCode(model.FundErrorType) {
+		t.Errorf("unknown fund = %d", status)
+	}
+
+	// owner 는 admin 을, admin 은 operator 만 지정할 수 있다.
+	if status, _ := invoke(stub, "setFundRole", string(admin.envelope(t, "F1,"+admin.address+",admin"))); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("self grant = %d", status)
+	}
+	if status, msg := invoke(stub, "setFundRole", string(owner.envelope(t, "F1,"+admin.address+",admin"))); status != shim.OK {
+		t.Fatal("grant admin failed", msg)
+	}
+	if status, msg := invoke(stub, "setFundRole", string(admin.envelope(t, "F1,"+operator.address+",operator"))); status != shim.OK {
+		t.Fatal("grant operator failed", msg)
+	}
+	if status, _ := invoke(stub, "setFundRole", string(admin.envelope(t, "F1,"+operator.address+",admin"))); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("admin granting admin = %d", status)
+	}
+	if status, _ := invoke(stub, "setFundRole", string(owner.envelope(t, "F1,"+operator.address+",owner"))); status != model.StatusCode(model.MandatoryPrameterErrorType) {
+		t.Errorf("grant owner role = %d", status)
+	}
+
+	for _, c := range []struct {
+		address, role, want string
+	}{
+		{owner.address, "admin", "true"},
+		{admin.address, "admin", "true"},
+		{admin.address, "owner", "false"},
+		{operator.address, "operator", "true"},
+		{operator.address, "admin", "false"},
+		{newOwner.address, "operator", "false"},
+	} {
+		if status, got := invoke(stub, "hasFundRole", "F1", c.address, c.role); status != shim.OK || got != c.want {
+			t.Errorf("hasFundRole %s = %d %s, want %s", c.role, status, got, c.want)
+		}
+	}
+
+	// 대표 관리자 이전은 새 관리자가 수락해야 한다.
+	if status, _ := invoke(stub, "transferFundAdmin", string(admin.envelope(t, "F1,"+newOwner.address))); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("transfer by admin = %d", status)
+	}
+	if status, msg := invoke(stub, "transferFundAdmin", string(owner.envelope(t, "F1,"+newOwner.address))); status != shim.OK {
+		t.Fatal("transferFundAdmin failed", msg)
+	}
+	if status, _ := invoke(stub, "acceptFundAdmin", string(admin.envelope(t, "F1"))); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("accept by other wallet = %d", status)
+	}
+	if _, got := invoke(stub, "fundAddressSearch", "F1"); got != owner.address {
+		t.Errorf("admin changed before accept: %s", got)
+	}
+	if status, msg := invoke(stub, "acceptFundAdmin", string(newOwner.envelope(t, "F1"))); status != shim.OK {
+		t.Fatal("acceptFundAdmin failed", msg)
+	}
+	if _, got := invoke(stub, "fundAddressSearch", "F1"); got != newOwner.address {
+		t.Errorf("fund admin %s, want %s", got, newOwner.address)
+	}
+	if _, got := invoke(stub, "hasFundRole", "F1", owner.address, "operator"); got != "false" {
+		t.Errorf("previous owner keeps role: %s", got)
+	}
+
+	// admin 을 해제하면 operator 를 지정할 수 없다.
+	if status, msg := invoke(stub, "setFundRole", string(newOwner.envelope(t, "F1,"+admin.address+","))); status != shim.OK {
+		t.Fatal("revoke admin failed", msg)
+	}
+	if status, _ := invoke(stub, "setFundRole", string(admin.envelope(t, "F1,"+owner.address+",operator"))); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("revoked admin grant = %d", status)
+	}
+}
+
+func TestFundSignerFromToken(t *testing.T) {
+	owner, device, other := newTestWallet(t), newTestWallet(t), newTestWallet(t)
+	token := &tokenChaincode{
+		accounts: map[string]string{device.address: owner.address},
+		roles:    map[string]bool{owner.address + "," + rbac.RoleOwner: true},
+	}
+	stub := newFundStub(t, token)
+
+	// 펀드 등록은 토큰 체인코드의 fundadmin 또는 owner 역할이 있어야 한다.
+	if status, _ := invoke(stub, "registerFund", string(other.envelope(t, "F1"))); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("registerFund without role = %d", status)
+	}
+	// 기존 방식 서명은 토큰 체인코드가 거부한다.
+	if status, _ := invoke(stub, "registerFund", string(owner.sign(t, wallet.WalletMeta{Transdata: "F1"}))); status != model.StatusCode(model.SignatureErrorType) {
+		t.Errorf("legacy registerFund = %d", status)
+	}
+
+	// 교체된 키로 서명하면 토큰 체인코드가 확인한 계정 주소가 대표 관리자가 된다.
+	if status, msg := invoke(stub, "registerFund", string(device.envelope(t, "F1"))); status != shim.OK {
+		t.Fatal("registerFund with rotated key failed", msg)
+	}
+	if status, admin := invoke(stub, "fundAddressSearch", "F1"); status != shim.OK || admin != owner.address {
+		t.Errorf("fundAddressSearch = %d %s, want %s", status, admin, owner.address)
+	}
+	if status, msg := invoke(stub, "setFundRole", string(device.envelope(t, "F1,"+other.address+",operator"))); status != shim.OK {
+		t.Fatal("setFundRole with rotated key failed", msg)
+	}
+}
+
+func TestFundInitRequiresTokenChaincode(t *testing.T) {
+	stub := shim.NewMockStub("fund", new(Chaincode))
+	if res := stub.MockInit("init", [][]byte{[]byte("init")}); res.Status != model.StatusCode(model.InitializeErrorType) {
+		t.Errorf("Init without token chaincode = %d", res.Status)
+	}
+	owner := newTestWallet(t)
+	if status, _ := invoke(stub, "registerFund", string(owner.envelope(t, "F1"))); status != model.StatusCode(model.InitializeErrorType) {
+		t.Errorf("registerFund before Init = %d", status)
+	}
+
+	// 설정된 뒤에는 업그레이드에서 이름을 생략할 수 있다.
+	if res := stub.MockInit("init", [][]byte{[]byte("init"), []byte(testTokenChaincode)}); res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+	if res := stub.MockInit("upgrade", [][]byte{[]byte("init")}); res.Status != shim.OK {
+		t.Error("upgrade Init failed", res.Message)
+	}
+}
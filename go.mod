@@ -5,7 +5,9 @@ go 1.13
 require (
 	github.com/Knetic/govaluate v3.0.0+incompatible // indirect
 	github.com/Shopify/sarama v1.27.2 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v3 v3.0.0
 	github.com/fsouza/go-dockerclient v1.6.0 // indirect
+	github.com/golang/protobuf v1.4.2
 	github.com/grpc-ecosystem/go-grpc-middleware v1.1.0 // indirect
 	github.com/hashicorp/go-version v1.2.1 // indirect
 	github.com/hyperledger/fabric v1.4.7
@@ -1,6 +1,12 @@
 package model
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
 
 const (
 	MarshalErrorType                     = "Marshal"
@@ -15,12 +21,82 @@ const (
 	CreateCompositeKeyErrorType          = "CreateCompositeKey"
 	GetStatePartialCompositeKeyErrorType = "GetStatePartialCompositeKey"
 	SpliteCompositeKeyErrorType          = "SpliteCompositeKey"
+	InsufficientBalanceErrorType         = "InsufficientBalance"
+	InsufficientAllowanceErrorType       = "InsufficientAllowance"
+	OverflowErrorType                    = "Overflow"
+	InitializeErrorType                  = "Initialize"
+	NonceErrorType                       = "Nonce"
+	PermissionErrorType                  = "Permission"
+	UnknownErrorType                     = "Unknown"
+	PublicKeyErrorType                   = "PublicKey"
+	HexDecodeErrorType                   = "HexDecode"
+	CurvePointErrorType                  = "CurvePoint"
+	AddressErrorType                     = "Address"
+	AccountErrorType                     = "Account"
+	DelegateErrorType                    = "Delegate"
+	FundErrorType                        = "Fund"
+	InvokeChaincodeErrorType             = "InvokeChaincode"
+)
+
+// SignatureErrorType 오류의 TypeName 으로 쓰는 사유 코드
+const (
+	SigReasonSigver          = "Sigver"
+	SigReasonDER             = "DER"
+	SigReasonTrailingData    = "TrailingData"
+	SigReasonNonCanonicalDER = "NonCanonicalDER"
+	SigReasonOutOfRange      = "OutOfRange"
+	SigReasonHighS           = "HighS"
+	SigReasonLength          = "Length"
+	SigReasonMismatch        = "Mismatch"
+	SigReasonMember          = "Member"
+	SigReasonDuplicate       = "Duplicate"
+	SigReasonThreshold       = "Threshold"
 )
 
+// errorStatus 는 ErrorType 별 응답 상태 코드. 호출자가 코드로 오류 종류를 구분하므로 값을 바꾸지 않는다.
+// 새 ErrorType 을 추가하면 여기에도 상태 코드를 추가한다.
+var errorStatus = map[string]int32{
+	MandatoryPrameterErrorType:           400,
+	SignatureErrorType:                   401,
+	PermissionErrorType:                  403,
+	TxTimeStampErrorType:                 408,
+	NonceErrorType:                       409,
+	InitializeErrorType:                  412,
+	UnMarshalErrorType:                   415,
+	ConvertErrorType:                     422,
+	PublicKeyErrorType:                   430,
+	HexDecodeErrorType:                   431,
+	CurvePointErrorType:                  432,
+	AddressErrorType:                     433,
+	AccountErrorType:                     434,
+	DelegateErrorType:                    435,
+	FundErrorType:                        436,
+	InsufficientBalanceErrorType:         460,
+	InsufficientAllowanceErrorType:       461,
+	OverflowErrorType:                    462,
+	MarshalErrorType:                     500,
+	InvokeChaincodeErrorType:             502,
+	PutStateErrorType:                    510,
+	GetStateErrorType:                    511,
+	SetEventErrorType:                    512,
+	CreateCompositeKeyErrorType:          513,
+	GetStatePartialCompositeKeyErrorType: 514,
+	SpliteCompositeKeyErrorType:          515,
+}
+
+// UnknownErrorStatus 는 CustomError 가 아니거나 등록되지 않은 ErrorType 의 상태 코드
+const UnknownErrorStatus int32 = 500
+
 type CustomError struct {
-	ErrorType string
-	TypeName  string
-	Message   string
+	ErrorType string `json:"errortype"`
+	TypeName  string `json:"typename"`
+	Message   string `json:"message"`
+
+	// 다른 체인코드 호출이 실패했을 때 호출한 체인코드, 함수와 그 응답 상태 코드
+	Chaincode string `json:"chaincode,omitempty"`
+	Function  string `json:"function,omitempty"`
+	Status    int32  `json:"status,omitempty"`
+	Payload   []byte `json:"payload,omitempty"` // 응답 payload 가 CustomError 가 아니면 원래 payload
 }
 
 func NewCustomError(errorType, typeName, message string) *CustomError {
@@ -32,5 +108,73 @@ func NewCustomError(errorType, typeName, message string) *CustomError {
 }
 
 func (e *CustomError) Error() string {
+	if e.Chaincode != "" {
+		return fmt.Sprintf("failed to %s %s, error: %s (chaincode %s %s)", e.ErrorType, e.TypeName, e.Message, e.Chaincode, e.Function)
+	}
 	return fmt.Sprintf("failed to %s %s, error: %s", e.ErrorType, e.TypeName, e.Message)
 }
+
+// InvokeError 는 다른 체인코드의 실패 응답을 CustomError 로 만든다.
+// 응답 payload 가 CustomError 이면 오류 종류와 내용을 그대로 두고, 호출한 chaincode, function 과 응답 상태 코드를 더한다.
+func InvokeError(chaincode, function string, response sc.Response) *CustomError {
+	customErr := decodeResponseError(response, chaincode+" "+function)
+	customErr.Chaincode = chaincode
+	customErr.Function = function
+	return customErr
+}
+
+// ResponseError 는 ErrorResponse 로 만든 응답을 다시 CustomError 로 디코딩한다. 성공 응답이면 nil 을 반환한다.
+func ResponseError(response sc.Response) *CustomError {
+	if response.Status < shim.ERRORTHRESHOLD {
+		return nil
+	}
+	return decodeResponseError(response, "")
+}
+
+// decodeResponseError 는 응답 payload 의 CustomError 를 디코딩한다.
+// payload 가 CustomError 가 아니면 InvokeChaincodeErrorType 으로 응답 메시지와 원래 payload 를 담는다.
+// 200 이 아닌 2xx, 3xx 응답을 그대로 전달하면 호출한 체인코드의 실패 응답이 성공으로 처리되므로
+// 상태 코드가 shim.ERRORTHRESHOLD 보다 작으면 InvokeChaincodeErrorType 의 상태 코드를 쓴다.
+func decodeResponseError(response sc.Response, typeName string) *CustomError {
+	customErr := &CustomError{}
+	if err := json.Unmarshal(response.Payload, customErr); err != nil || customErr.ErrorType == "" {
+		customErr = NewCustomError(InvokeChaincodeErrorType, typeName, response.Message)
+		customErr.Payload = response.Payload
+	}
+	customErr.Status = response.Status
+	if customErr.Status < shim.ERRORTHRESHOLD {
+		customErr.Status = StatusCode(InvokeChaincodeErrorType)
+	}
+	return customErr
+}
+
+// StatusCode 는 ErrorType 의 응답 상태 코드를 반환한다.
+func StatusCode(errorType string) int32 {
+	if status, ok := errorStatus[errorType]; ok {
+		return status
+	}
+	return UnknownErrorStatus
+}
+
+// ToCustomError 는 err 를 CustomError 로 변환한다. CustomError 가 아니면 UnknownErrorType 으로 감싼다.
+func ToCustomError(err error) *CustomError {
+	if err == nil {
+		return nil
+	}
+	if customErr, ok := err.(*CustomError); ok {
+		return customErr
+	}
+	return NewCustomError(UnknownErrorType, "", err.Error())
+}
+
+// ErrorResponse 는 err 를 ErrorType 별 상태 코드의 응답으로 만든다. 다른 체인코드에서 받은 오류이면 받은 상태 코드로 응답한다.
+// Payload 에는 CustomError JSON 을 담아 호출한 체인코드가 다시 디코딩할 수 있게 한다.
+func ErrorResponse(err error) sc.Response {
+	customErr := ToCustomError(err)
+	payload, _ := json.Marshal(customErr)
+	status := StatusCode(customErr.ErrorType)
+	if customErr.Status >= shim.ERRORTHRESHOLD {
+		status = customErr.Status
+	}
+	return sc.Response{Status: status, Message: customErr.Error(), Payload: payload}
+}
@@ -0,0 +1,51 @@
+package model
+
+// 펀드 관리자 역할. owner 는 admin, operator 권한을 포함하고 admin 은 operator 권한을 포함한다.
+const (
+	FundRoleOwner    = "owner"    // 대표 관리자 (fundAddressSearch 결과), admin 지정과 대표 관리자 이전
+	FundRoleAdmin    = "admin"    // operator 지정
+	FundRoleOperator = "operator" // 펀드 운용
+)
+
+// Fund is 펀드 레지스트리 항목
+type Fund struct {
+	FundID       string       `json:"fundid"`
+	Admin        string       `json:"admin"`                  // 대표 관리자 (owner)
+	PendingAdmin string       `json:"pendingadmin,omitempty"` // acceptFundAdmin 을 기다리는 새 대표 관리자
+	Members      []FundMember `json:"members,omitempty"`      // admin, operator
+}
+
+// FundMember is 대표 관리자 외의 펀드 관리자
+type FundMember struct {
+	Address string `json:"address"`
+	Role    string `json:"role"`
+}
+
+// FundRole 은 address 의 펀드 역할을 반환한다. 관리자가 아니면 "" 를 반환한다.
+func (f *Fund) FundRole(address string) string {
+	if address == f.Admin {
+		return FundRoleOwner
+	}
+	for _, member := range f.Members {
+		if member.Address == address {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+// HasFundRole 은 address 가 role 이상의 권한을 가졌는지 확인한다.
+func (f *Fund) HasFundRole(address, role string) bool {
+	return fundRoleRank[f.FundRole(address)] >= fundRoleRank[role] && fundRoleRank[role] > 0
+}
+
+// ValidFundRole 은 setFundRole 로 지정할 수 있는 역할인지 확인한다.
+func ValidFundRole(role string) bool {
+	return role == FundRoleAdmin || role == FundRoleOperator
+}
+
+var fundRoleRank = map[string]int{
+	FundRoleOperator: 1,
+	FundRoleAdmin:    2,
+	FundRoleOwner:    3,
+}
@@ -0,0 +1,44 @@
+package model
+
+// TokenInfo is 토큰 정의 (Init 파라미터 및 tokenInfo 조회 결과)
+type TokenInfo struct {
+	Name          string `json:"name"`
+	Symbol        string `json:"symbol"`
+	Decimals      uint8  `json:"decimals"`
+	InitialSupply uint64 `json:"initialsupply"`
+	Owner         string `json:"owner"`
+	LegacyOwner   bool   `json:"legacyowner,omitempty"` // Owner 가 기존 형식 주소이면 true
+	Cap           uint64 `json:"cap,omitempty"`
+
+	// TxTime 은 Init 에서만 받는 초기 tx-time 허용 범위 (별도 원장 키로 저장)
+	TxTime *TxTimeConfig `json:"txtime,omitempty"`
+}
+
+// TxTimeConfig is 지갑 트랜잭션 txtime 허용 범위 (초)
+// PastSec 은 txtime 이 proposal timestamp 보다 이전일 수 있는 범위, FutureSec 은 이후일 수 있는 범위
+// proposal timestamp 는 클라이언트가 정하는 값이므로 이 범위는 클라이언트 시계 차이를 제한할 뿐 원장 시간을 보장하지 않는다.
+// Disabled 는 테스트 네트워크에서 검사를 끌 때 사용한다.
+type TxTimeConfig struct {
+	PastSec   int64 `json:"pastsec"`
+	FutureSec int64 `json:"futuresec"`
+	Disabled  bool  `json:"disabled,omitempty"`
+}
+
+// SigConfig is 지갑 서명 방식 설정
+// DisableSHA1 이 true 이면 SHA-1 digest 를 쓰는 ECDSA 서명(sigver 1, 2)을 받지 않는다.
+type SigConfig struct {
+	DisableSHA1 bool `json:"disablesha1"`
+}
+
+// BalanceBatch is balanceOfBatch 조회 결과
+type BalanceBatch struct {
+	Decimals uint8          `json:"decimals"`
+	Balances []BalanceEntry `json:"balances"` // 요청한 주소 순서
+}
+
+// BalanceEntry is 주소 하나의 잔액
+// Balance 는 최소 단위 금액의 10진수 문자열이다. uint64 를 넘는 토큰도 같은 형식을 쓸 수 있게 숫자 대신 문자열로 둔다.
+type BalanceEntry struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"`
+}
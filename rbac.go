@@ -0,0 +1,172 @@
+/*
+ * SejongTelecom 코어기술개발팀
+ * @author JinSan
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/rbac"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+const (
+	roleGrantedEventName = "RoleGranted"
+	roleRevokedEventName = "RoleRevoked"
+)
+
+// RoleEvent is RoleGranted, RoleRevoked 이벤트
+type RoleEvent struct {
+	Role    string `json:"role"`
+	Address string `json:"address"`
+	Sender  string `json:"sender"`
+}
+
+// GrantRole is 지갑형 역할 부여 (owner 역할만 가능)
+// transdata - address, role
+func (cc *Chaincode) GrantRole(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, address, role, err := roleParams(stub, "grantRole")
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	if err := rbac.GrantRole(stub, address, role); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := setEvent(stub, roleGrantedEventName, RoleEvent{Role: role, Address: address, Sender: walletParams.Address}); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// RevokeRole is 지갑형 역할 회수 (owner 역할만 가능)
+// owner 는 자기 owner 역할을 회수할 수 없으므로 owner 가 한 명 이상 남는다.
+// transdata - address, role
+func (cc *Chaincode) RevokeRole(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, address, role, err := roleParams(stub, "revokeRole")
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if role == rbac.RoleOwner && (address == walletParams.Address || address == walletParams.LegacyAddress) {
+		return model.ErrorResponse(model.NewCustomError(model.PermissionErrorType, address, "owner cannot revoke own owner role"))
+	}
+
+	if err := rbac.RevokeRole(stub, address, role); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := setEvent(stub, roleRevokedEventName, RoleEvent{Role: role, Address: address, Sender: walletParams.Address}); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// HasRole is 역할 확인 ("true" 또는 "false")
+// params - address, role
+func (cc *Chaincode) HasRole(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 2 || args[0] == "" || args[1] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "hasRole", "incorrect number of arguments, expecting address,role"))
+	}
+	address, err := wallet.ResolveAddress(stub, args[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	ok, err := rbac.HasRole(stub, address, args[1])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success([]byte(strconv.FormatBool(ok)))
+}
+
+// GetRoleMembers is 역할이 있는 지갑주소 목록 조회 (JSON 배열)
+// params - role
+func (cc *Chaincode) GetRoleMembers(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 || args[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "getRoleMembers", "incorrect number of arguments, expecting role"))
+	}
+	members, err := rbac.RoleMembers(stub, args[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	membersBytes, err := json.Marshal(members)
+	if err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.MarshalErrorType, "RoleMembers", err.Error()))
+	}
+	return shim.Success(membersBytes)
+}
+
+// roleParams 는 grantRole, revokeRole 의 서명자가 owner 역할인지 확인하고 대상 주소와 역할을 반환한다.
+func roleParams(stub shim.ChaincodeStubInterface, function string) (*wallet.WalletParams, string, string, error) {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return nil, "", "", walletErr
+	}
+	params := walletParams.Params
+	if len(params) != 2 || params[0] == "" || params[1] == "" {
+		return nil, "", "", model.NewCustomError(model.MandatoryPrameterErrorType, function, "transdata must be address,role")
+	}
+	if !rbac.ValidRole(params[1]) {
+		return nil, "", "", model.NewCustomError(model.MandatoryPrameterErrorType, "role", "unknown role "+params[1])
+	}
+
+	if err := requireOwnerSig(stub, walletParams); err != nil {
+		return nil, "", "", err
+	}
+
+	address, err := resolveRecipient(stub, params[0])
+	if err != nil {
+		return nil, "", "", err
+	}
+	return walletParams, address, params[1], nil
+}
+
+// requireOwnerSig 는 역할이나 체인코드 설정을 바꾸는 서명자가 owner 역할이고, 지갑 자신의 키로 canonical 서명했는지 확인한다.
+// 위임 키나 transdata 를 서명하지 않는 기존 방식 서명으로는 관리자 권한을 쓸 수 없다.
+func requireOwnerSig(stub shim.ChaincodeStubInterface, walletParams *wallet.WalletParams) error {
+	if err := wallet.RequireOwnKey(walletParams); err != nil {
+		return err
+	}
+	if err := wallet.RequireCanonicalSig(walletParams); err != nil {
+		return err
+	}
+	return requireRole(stub, walletParams, rbac.RoleOwner)
+}
+
+// requireRole 은 서명자에게 role 이 없으면 오류를 반환한다.
+// 기존 형식 주소로 받은 역할도 같은 키의 서명이면 인정한다.
+func requireRole(stub shim.ChaincodeStubInterface, walletParams *wallet.WalletParams, role string) error {
+	if walletParams.LegacyAddress != "" {
+		ok, err := rbac.HasRole(stub, walletParams.LegacyAddress, role)
+		if err != nil || ok {
+			return err
+		}
+	}
+	return rbac.RequireRole(stub, walletParams.Address, role)
+}
+
+// bootstrapOwnerRole 은 owner 역할이 없으면 토큰 정의의 owner 에게 부여한다.
+// RBAC 이전에 배포된 토큰을 업그레이드할 때 기존 owner 권한을 이어받는다.
+func bootstrapOwnerRole(stub shim.ChaincodeStubInterface, info *model.TokenInfo) error {
+	owners, err := rbac.RoleMembers(stub, rbac.RoleOwner)
+	if err != nil || len(owners) > 0 {
+		return err
+	}
+	if err := validateOwner(info); err != nil {
+		return err
+	}
+	owner, err := wallet.ResolveAddress(stub, info.Owner)
+	if err != nil {
+		return err
+	}
+	return rbac.GrantRole(stub, owner, rbac.RoleOwner)
+}
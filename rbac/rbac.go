@@ -0,0 +1,131 @@
+// Package rbac 는 지갑주소별 역할(role) 권한을 관리한다.
+//
+// 역할은 체인코드 원장에 (role, address) 복합키로 저장한다. 부여와 회수를 누가 할 수 있는지는 호출하는 핸들러가
+// vaildWallet 으로 서명자를 확인한 뒤 RequireRole 로 검사한다.
+package rbac
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+)
+
+// 역할 복합키 (role, address -> "1")
+const roleKeyType = "rbacRole"
+
+// 역할
+const (
+	RoleOwner      = "owner"      // 역할 부여/회수와 체인코드 설정 변경
+	RoleMinter     = "minter"     // 토큰 발행
+	RoleBurner     = "burner"     // 토큰 소각
+	RolePauser     = "pauser"     // 거래 일시 중지
+	RoleCompliance = "compliance" // 컴플라이언스 담당자
+	RoleFundAdmin  = "fundadmin"  // 펀드 등록 (fund 체인코드 registerFund)
+)
+
+// Roles 는 부여할 수 있는 역할 목록
+var Roles = []string{RoleOwner, RoleMinter, RoleBurner, RolePauser, RoleCompliance, RoleFundAdmin}
+
+// ValidRole 은 role 이 정의된 역할인지 확인한다.
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole 은 address 에 role 이 있는지 확인한다.
+func HasRole(stub shim.ChaincodeStubInterface, address string, role string) (bool, error) {
+	if address == "" {
+		return false, nil
+	}
+	key, err := roleKey(stub, role, address)
+	if err != nil {
+		return false, err
+	}
+	value, err := stub.GetState(key)
+	if err != nil {
+		return false, model.NewCustomError(model.GetStateErrorType, key, err.Error())
+	}
+	return value != nil, nil
+}
+
+// RequireRole 은 address 에 role 이 없으면 PermissionErrorType 오류를 반환한다.
+func RequireRole(stub shim.ChaincodeStubInterface, address string, role string) error {
+	ok, err := HasRole(stub, address, role)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return model.NewCustomError(model.PermissionErrorType, address, "requires role "+role)
+	}
+	return nil
+}
+
+// GrantRole 은 address 에 role 을 부여한다. 권한 확인은 호출하는 쪽에서 한다.
+func GrantRole(stub shim.ChaincodeStubInterface, address string, role string) error {
+	if address == "" {
+		return model.NewCustomError(model.MandatoryPrameterErrorType, "address", "address is empty")
+	}
+	key, err := roleKey(stub, role, address)
+	if err != nil {
+		return err
+	}
+	if err := stub.PutState(key, []byte("1")); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	return nil
+}
+
+// RevokeRole 은 address 의 role 을 회수한다. 권한 확인은 호출하는 쪽에서 한다.
+func RevokeRole(stub shim.ChaincodeStubInterface, address string, role string) error {
+	key, err := roleKey(stub, role, address)
+	if err != nil {
+		return err
+	}
+	if err := stub.DelState(key); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	return nil
+}
+
+// RoleMembers 는 role 이 있는 지갑주소 목록을 반환한다.
+func RoleMembers(stub shim.ChaincodeStubInterface, role string) ([]string, error) {
+	if !ValidRole(role) {
+		return nil, model.NewCustomError(model.MandatoryPrameterErrorType, "role", "unknown role "+role)
+	}
+	iter, err := stub.GetStateByPartialCompositeKey(roleKeyType, []string{role})
+	if err != nil {
+		return nil, model.NewCustomError(model.GetStatePartialCompositeKeyErrorType, roleKeyType, err.Error())
+	}
+	defer iter.Close()
+
+	members := []string{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, model.NewCustomError(model.GetStatePartialCompositeKeyErrorType, roleKeyType, err.Error())
+		}
+		_, attrs, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, model.NewCustomError(model.SpliteCompositeKeyErrorType, kv.Key, err.Error())
+		}
+		if len(attrs) != 2 {
+			return nil, model.NewCustomError(model.SpliteCompositeKeyErrorType, kv.Key, "malformed role key")
+		}
+		members = append(members, attrs[1])
+	}
+	return members, nil
+}
+
+func roleKey(stub shim.ChaincodeStubInterface, role string, address string) (string, error) {
+	if !ValidRole(role) {
+		return "", model.NewCustomError(model.MandatoryPrameterErrorType, "role", "unknown role "+role)
+	}
+	key, err := stub.CreateCompositeKey(roleKeyType, []string{role, address})
+	if err != nil {
+		return "", model.NewCustomError(model.CreateCompositeKeyErrorType, roleKeyType, err.Error())
+	}
+	return key, nil
+}
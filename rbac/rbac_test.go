@@ -0,0 +1,44 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+)
+
+func TestRequireRole(t *testing.T) {
+	stub := shim.NewMockStub("rbac", nil)
+	stub.MockTransactionStart("roles")
+	defer stub.MockTransactionEnd("roles")
+	const alice, bob = "alice", "bob"
+
+	if err := GrantRole(stub, alice, RoleMinter); err != nil {
+		t.Fatal(err)
+	}
+	if err := GrantRole(stub, bob, RoleMinter); err != nil {
+		t.Fatal(err)
+	}
+	if err := RequireRole(stub, alice, RoleMinter); err != nil {
+		t.Error(err)
+	}
+	if customErr := model.ToCustomError(RequireRole(stub, alice, RoleBurner)); customErr == nil || customErr.ErrorType != model.PermissionErrorType {
+		t.Errorf("missing role: %v", customErr)
+	}
+	if customErr := model.ToCustomError(GrantRole(stub, alice, "admin")); customErr == nil || customErr.ErrorType != model.MandatoryPrameterErrorType {
+		t.Errorf("unknown role: %v", customErr)
+	}
+	if ok, err := HasRole(stub, "", RoleMinter); ok || err != nil {
+		t.Errorf("empty address has role: %v %v", ok, err)
+	}
+
+	if err := RevokeRole(stub, alice, RoleMinter); err != nil {
+		t.Fatal(err)
+	}
+	if err := RequireRole(stub, alice, RoleMinter); err == nil {
+		t.Error("revoked role still required")
+	}
+	if members, err := RoleMembers(stub, RoleMinter); err != nil || len(members) != 1 || members[0] != bob {
+		t.Errorf("minters %v, err %v", members, err)
+	}
+}
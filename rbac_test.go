@@ -0,0 +1,137 @@
+/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/rbac"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+func TestRoleGrantAndRevoke(t *testing.T) {
+	stub := shim.NewMockStub(testChaincodeName, new(Chaincode))
+	alice, bob, carol := newTestWallet(t), newTestWallet(t), newTestWallet(t)
+	aliceAddr, bobAddr, carolAddr := alice.address(t, stub), bob.address(t, stub), carol.address(t, stub)
+	def := `{"name":"Sejong Token","symbol":"SJT","initialsupply":1000,"owner":"` + aliceAddr + `"}`
+	if res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(def)}); res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+	expectQuery(t, stub, "true", "hasRole", aliceAddr, "owner")
+
+	if status, _ := bob.call(t, stub, "grantRole", wallet.WalletMeta{Transdata: bobAddr + ",minter"}); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("grant by non owner = %d", status)
+	}
+	if status, _ := alice.call(t, stub, "grantRole", wallet.WalletMeta{Transdata: bobAddr + ",admin"}); status != model.StatusCode(model.MandatoryPrameterErrorType) {
+		t.Errorf("grant unknown role = %d", status)
+	}
+	if status, msg := alice.call(t, stub, "grantRole", wallet.WalletMeta{Transdata: bobAddr + ",minter"}); status != shim.OK {
+		t.Fatal("grantRole failed", msg)
+	}
+	expectQuery(t, stub, "true", "hasRole", bobAddr, "minter")
+	expectQuery(t, stub, "false", "hasRole", bobAddr, "burner")
+	expectQuery(t, stub, `["`+bobAddr+`"]`, "getRoleMembers", "minter")
+	if status, _ := bob.call(t, stub, "grantRole", wallet.WalletMeta{Transdata: carolAddr + ",minter"}); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("grant by minter = %d", status)
+	}
+
+	// owner 는 자기 owner 역할을 회수할 수 없고, 다른 owner 가 회수하면 설정 권한도 잃는다.
+	if status, _ := alice.call(t, stub, "revokeRole", wallet.WalletMeta{Transdata: aliceAddr + ",owner"}); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("self revoke = %d", status)
+	}
+	if status, msg := alice.call(t, stub, "grantRole", wallet.WalletMeta{Transdata: carolAddr + ",owner"}); status != shim.OK {
+		t.Fatal("grant owner failed", msg)
+	}
+	if status, msg := carol.call(t, stub, "revokeRole", wallet.WalletMeta{Transdata: aliceAddr + ",owner"}); status != shim.OK {
+		t.Fatal("revokeRole failed", msg)
+	}
+	sigCfg := wallet.WalletMeta{Transjdata: `{"disablesha1":false}`}
+	if status, _ := alice.call(t, stub, "setSigConfig", sigCfg); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("setSigConfig by revoked owner = %d", status)
+	}
+	if status, msg := carol.call(t, stub, "setSigConfig", sigCfg); status != shim.OK {
+		t.Error("setSigConfig by owner failed", msg)
+	}
+}
+
+func TestAdminRequiresCanonicalSignature(t *testing.T) {
+	stub := shim.NewMockStub(testChaincodeName, new(Chaincode))
+	owner, session := newTestWallet(t), newTestWallet(t)
+	ownerAddr, sessionAddr := owner.address(t, stub), session.address(t, stub)
+	def := `{"name":"Sejong Token","symbol":"SJT","initialsupply":1000,"owner":"` + ownerAddr + `"}`
+	if res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(def)}); res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+
+	// 기존 방식은 transdata 를 서명하지 않으므로 owner 의 봉투라도 관리자 함수에 쓸 수 없다.
+	legacy := []struct {
+		function string
+		envelope []byte
+	}{
+		{"grantRole", owner.envelope(t, testBob+",minter")},
+		{"revokeRole", owner.envelope(t, ownerAddr+",burner")},
+		{"setTxTimeWindow", owner.sign(t, wallet.WalletMeta{Transjdata: `{"disabled":true}`}, wallet.SigContext{})},
+		{"setSigConfig", owner.sign(t, wallet.WalletMeta{Transjdata: `{"disablesha1":true}`}, wallet.SigContext{})},
+	}
+	for _, tc := range legacy {
+		if status, _ := invoke(stub, []byte(tc.function), tc.envelope); status != model.StatusCode(model.SignatureErrorType) {
+			t.Errorf("legacy %s = %d", tc.function, status)
+		}
+	}
+	expectQuery(t, stub, "false", "hasRole", testBob, "minter")
+
+	// 설정 변경도 위임 키로 할 수 없다.
+	expiry := strconv.FormatInt(time.Now().Unix()+3600, 10)
+	delegate := wallet.WalletMeta{Transjdata: `{"delegate":"` + sessionAddr + `","expiry":` + expiry + `,"functions":["setTxTimeWindow","setSigConfig"],"spendlimit":0}`}
+	if status, msg := owner.call(t, stub, "registerDelegate", delegate); status != shim.OK {
+		t.Fatal("registerDelegate failed", msg)
+	}
+	for _, function := range []string{"setTxTimeWindow", "setSigConfig"} {
+		if status, _ := session.call(t, stub, function, wallet.WalletMeta{Transjdata: `{}`}); status != model.StatusCode(model.PermissionErrorType) {
+			t.Errorf("delegate %s = %d", function, status)
+		}
+	}
+}
+
+func TestOwnerRoleBootstrapOnUpgrade(t *testing.T) {
+	stub := shim.NewMockStub("chaincode", new(Chaincode))
+	def := `{"name":"Sejong Token","symbol":"SJT","initialsupply":1000,"owner":"` + testBob + `"}`
+	if res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(def)}); res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+
+	// RBAC 이전에 배포된 토큰처럼 owner 역할을 지운다.
+	stub.MockTransactionStart("legacy")
+	if err := rbac.RevokeRole(stub, testBob, rbac.RoleOwner); err != nil {
+		t.Fatal(err)
+	}
+	stub.MockTransactionEnd("legacy")
+	expectQuery(t, stub, "[]", "getRoleMembers", "owner")
+
+	if res := stub.MockInit("2", [][]byte{[]byte("init")}); res.Status != shim.OK {
+		t.Fatal("upgrade Init failed", res.Message)
+	}
+	expectQuery(t, stub, `["`+testBob+`"]`, "getRoleMembers", "owner")
+}
+
+func TestOwnerRoleBootstrapRejectsInvalidOwner(t *testing.T) {
+	stub := shim.NewMockStub("chaincode", new(Chaincode))
+
+	// 주소 확인 전에 저장된 토큰 정의처럼 owner 에 오타가 있다.
+	stub.MockTransactionStart("legacy")
+	if err := stub.PutState(tokenInfoKey, []byte(`{"name":"Sejong Token","symbol":"SJT","initialsupply":1000,"owner":"owner"}`)); err != nil {
+		t.Fatal(err)
+	}
+	stub.MockTransactionEnd("legacy")
+
+	if res := stub.MockInit("2", [][]byte{[]byte("init")}); res.Status != model.StatusCode(model.AddressErrorType) {
+		t.Errorf("upgrade Init with invalid owner = %d %s", res.Status, res.Message)
+	}
+	expectQuery(t, stub, "[]", "getRoleMembers", "owner")
+}
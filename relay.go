@@ -0,0 +1,86 @@
+/*
+ * SejongTelecom 코어기술개발팀
+ * @author JinSan
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+// RelayMeta is 중계(meta-transaction) 전송의 transjdata
+// 사용자가 서명한 봉투를 relayer 가 제출하고, 전송과 함께 Fee 만큼 토큰을 Relayer 지갑으로 받는다.
+type RelayMeta struct {
+	Relayer    string `json:"relayer,omitempty"`    // 수수료를 받을 지갑주소
+	RelayerMSP string `json:"relayermsp,omitempty"` // 제출할 수 있는 MSP ID, 비어 있으면 제한하지 않는다
+	Fee        uint64 `json:"fee,omitempty"`
+}
+
+// relay is 확인된 중계 정보
+type relay struct {
+	relayer    string
+	relayerMSP string // 트랜잭션을 제출한 MSP ID
+	fee        uint64
+}
+
+// parseRelay 는 transjdata 의 중계 정보를 확인한다. transjdata 가 없으면 nil 을 반환한다.
+// 같은 봉투를 다시 제출해서 수수료를 두 번 받지 못하도록 nonce 로 서명한 봉투만 받는다.
+// nonce 는 canonical 서명에만 쓸 수 있으므로 중계 정보도 서명에 포함된다.
+func parseRelay(stub shim.ChaincodeStubInterface, walletParams *wallet.WalletParams) (*relay, error) {
+	if walletParams.Jdata == "" {
+		return nil, nil
+	}
+
+	relayMeta := RelayMeta{}
+	decoder := json.NewDecoder(strings.NewReader(walletParams.Jdata))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&relayMeta); err != nil {
+		return nil, model.NewCustomError(model.UnMarshalErrorType, "RelayMeta", err.Error())
+	}
+	if walletParams.Nonce == "" {
+		return nil, model.NewCustomError(model.NonceErrorType, "RelayMeta", "relayed transfer requires nonce")
+	}
+	if relayMeta.Fee > 0 && relayMeta.Relayer == "" {
+		return nil, model.NewCustomError(model.MandatoryPrameterErrorType, "relayer", "fee requires relayer address")
+	}
+
+	mspID, err := creatorMSPID(stub)
+	if err != nil {
+		return nil, err
+	}
+	if relayMeta.RelayerMSP != "" && relayMeta.RelayerMSP != mspID {
+		return nil, model.NewCustomError(model.PermissionErrorType, mspID, "transaction must be submitted by "+relayMeta.RelayerMSP)
+	}
+
+	r := &relay{relayerMSP: mspID, fee: relayMeta.Fee}
+	if relayMeta.Relayer != "" {
+		if r.relayer, err = resolveRecipient(stub, relayMeta.Relayer); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// creatorMSPID 는 트랜잭션을 제출한 identity 의 MSP ID
+func creatorMSPID(stub shim.ChaincodeStubInterface) (string, error) {
+	creator, err := stub.GetCreator()
+	if err != nil {
+		return "", model.NewCustomError(model.UnMarshalErrorType, "Creator", err.Error())
+	}
+	identity := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(creator, identity); err != nil {
+		return "", model.NewCustomError(model.UnMarshalErrorType, "SerializedIdentity", err.Error())
+	}
+	if identity.Mspid == "" {
+		return "", model.NewCustomError(model.UnMarshalErrorType, "SerializedIdentity", "creator has no MSP ID")
+	}
+	return identity.Mspid, nil
+}
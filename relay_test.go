@@ -0,0 +1,115 @@
+/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+// creatorChaincode 는 MockStub 이 돌려주지 않는 creator 를 채워서 Chaincode 를 호출한다.
+type creatorChaincode struct {
+	*Chaincode
+	mspID string
+}
+
+type creatorStub struct {
+	shim.ChaincodeStubInterface
+	creator []byte
+}
+
+func (s *creatorStub) GetCreator() ([]byte, error) { return s.creator, nil }
+
+func (cc *creatorChaincode) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
+	creator, _ := proto.Marshal(&msp.SerializedIdentity{Mspid: cc.mspID})
+	return cc.Chaincode.Invoke(&creatorStub{ChaincodeStubInterface: stub, creator: creator})
+}
+
+func lastTransferEvent(t *testing.T, stub *shim.MockStub) TransferEvent {
+	event := TransferEvent{}
+	for {
+		select {
+		case e := <-stub.ChaincodeEventsChannel:
+			if e.EventName == transferEventName {
+				event = TransferEvent{}
+				if err := json.Unmarshal(e.Payload, &event); err != nil {
+					t.Fatal(err)
+				}
+			}
+		default:
+			return event
+		}
+	}
+}
+
+func TestRelayedTransfer(t *testing.T) {
+	stub := shim.NewMockStub("erc20", &creatorChaincode{Chaincode: new(Chaincode), mspID: "RelayerMSP"})
+	stub.ChannelID = "mychannel"
+	alice, relayer := newTestWallet(t), newTestWallet(t)
+	aliceAddr, relayerAddr := alice.address(t, stub), relayer.address(t, stub)
+	seedBalance(t, stub, aliceAddr, 100)
+	ctx := wallet.SigContext{Function: "transfer", Channel: "mychannel", Chaincode: "erc20"}
+
+	relayed := func(nonce, jdata, transdata string) []byte {
+		return alice.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerCanonical, Nonce: nonce, Transdata: transdata, Transjdata: jdata}, ctx)
+	}
+	fee := `{"relayer":"` + relayerAddr + `","relayermsp":"RelayerMSP","fee":5}`
+
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), alice.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerCanonical, Transdata: testBob + ",10", Transjdata: fee}, ctx)); status != model.StatusCode(model.NonceErrorType) {
+		t.Errorf("relay without nonce = %d", status)
+	}
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), relayed("1", `{"relayer":"`+relayerAddr+`","relayermsp":"OtherMSP","fee":5}`, testBob+",10")); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("relay by other MSP = %d", status)
+	}
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), relayed("2", `{"fee":5}`, testBob+",10")); status != model.StatusCode(model.MandatoryPrameterErrorType) {
+		t.Errorf("fee without relayer = %d", status)
+	}
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), relayed("3", fee, testBob+",96")); status != model.StatusCode(model.InsufficientBalanceErrorType) {
+		t.Errorf("amount and fee over balance = %d", status)
+	}
+	lastTransferEvent(t, stub)
+
+	// MockStub 은 실패한 트랜잭션의 nonce 를 되돌리지 않으므로 새 nonce 를 쓴다.
+	signed := relayed("4", fee, testBob+",10")
+	if status, msg := invokeSigned(t, stub, []byte("transfer"), signed); status != shim.OK {
+		t.Fatal("relayed transfer failed", msg)
+	}
+	expectQuery(t, stub, "85", "balanceOf", aliceAddr)
+	expectQuery(t, stub, "10", "balanceOf", testBob)
+	expectQuery(t, stub, "5", "balanceOf", relayerAddr)
+	want := TransferEvent{From: aliceAddr, To: testBob, Value: 10, Relayer: relayerAddr, RelayerMSP: "RelayerMSP", Fee: 5}
+	if event := lastTransferEvent(t, stub); event != want {
+		t.Errorf("transfer event %+v, want %+v", event, want)
+	}
+
+	// 같은 봉투를 다시 제출해도 수수료를 다시 받을 수 없다.
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), signed); status == shim.OK {
+		t.Error("replayed relay succeeded")
+	}
+
+	// 서명된 수수료를 바꾸면 서명이 맞지 않는다.
+	meta := wallet.WalletMeta{}
+	json.Unmarshal(relayed("5", fee, testBob+",10"), &meta)
+	meta.Transjdata = `{"relayer":"` + relayerAddr + `","relayermsp":"RelayerMSP","fee":50}`
+	tampered, _ := json.Marshal(meta)
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), tampered); status != model.StatusCode(model.SignatureErrorType) {
+		t.Errorf("tampered fee = %d", status)
+	}
+
+	// 수수료를 relayer 자신에게 보내는 경우도 한 번에 처리한다.
+	if status, msg := invokeSigned(t, stub, []byte("transfer"), relayed("5", fee, relayerAddr+",10")); status != shim.OK {
+		t.Fatal("transfer to relayer failed", msg)
+	}
+	expectQuery(t, stub, "70", "balanceOf", aliceAddr)
+	expectQuery(t, stub, "20", "balanceOf", relayerAddr)
+	expectQuery(t, stub, "100", "totalSupply")
+}
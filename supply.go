@@ -0,0 +1,144 @@
+/*
+ * SejongTelecom 코어기술개발팀
+ * @author JinSan
+ */
+
+package main
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/rbac"
+	"github.com/jinsan74/Erc20/utils"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+// Mint is 지갑형 토큰 발행 (minter 역할만 가능)
+// 위임 키나 기존 서명 형식(sigver 1)으로는 발행할 수 없다.
+// 총 발행량이 토큰 정의의 cap 을 넘을 수 없다. cap 이 0 이면 제한이 없다.
+// transdata - toaddress, amount
+func (cc *Chaincode) Mint(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	if err := wallet.RequireOwnKey(walletParams); err != nil {
+		return model.ErrorResponse(err)
+	}
+	// 기존 서명 형식은 transdata 를 서명하지 않으므로 다른 거래의 봉투를 발행/소각에 재사용할 수 있다.
+	if err := wallet.RequireCanonicalSig(walletParams); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := requireRole(stub, walletParams, rbac.RoleMinter); err != nil {
+		return model.ErrorResponse(err)
+	}
+	params := walletParams.Params
+	if len(params) != 2 || params[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "mint", "transdata must be toaddress,amount"))
+	}
+
+	to, err := resolveRecipient(stub, params[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	amount, err := utils.ConvertStringToUint64("amount", params[1])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	info, err := requireTokenInfo(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	supply, err := getUint64State(stub, totalSupplyKey)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if supply+*amount < supply {
+		return model.ErrorResponse(model.NewCustomError(model.OverflowErrorType, "totalsupply", "total supply overflow"))
+	}
+	if info.Cap > 0 && supply+*amount > info.Cap {
+		return model.ErrorResponse(model.NewCustomError(model.OverflowErrorType, "cap", "amount exceeds cap"))
+	}
+	balance, err := getBalance(stub, to)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if balance+*amount < balance {
+		return model.ErrorResponse(model.NewCustomError(model.OverflowErrorType, to, "balance overflow"))
+	}
+
+	if err := putUint64State(stub, totalSupplyKey, supply+*amount); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := putBalance(stub, to, balance+*amount); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := setEvent(stub, transferEventName, TransferEvent{From: wallet.ZeroAddress, To: to, Value: *amount}); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// Burn is 지갑형 토큰 소각 (burner 역할만 가능)
+// 위임 키나 기존 서명 형식(sigver 1)으로는 소각할 수 없다.
+// 서명자 지갑의 잔액에서 amount 를 소각하고 총 발행량을 줄인다.
+// transdata - amount
+func (cc *Chaincode) Burn(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	if err := wallet.RequireOwnKey(walletParams); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := wallet.RequireCanonicalSig(walletParams); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := requireRole(stub, walletParams, rbac.RoleBurner); err != nil {
+		return model.ErrorResponse(err)
+	}
+	params := walletParams.Params
+	if len(params) != 1 || params[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "burn", "transdata must be amount"))
+	}
+
+	from := walletParams.Address
+	amount, err := utils.ConvertStringToUint64("amount", params[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if _, err := requireTokenInfo(stub); err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	balance, err := getBalance(stub, from)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if balance < *amount {
+		return model.ErrorResponse(model.NewCustomError(model.InsufficientBalanceErrorType, from, "amount exceeds balance"))
+	}
+	supply, err := getUint64State(stub, totalSupplyKey)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	// 잔액의 합은 총 발행량을 넘을 수 없으므로 여기에 걸리면 원장이 어긋난 것이다.
+	if supply < *amount {
+		return model.ErrorResponse(model.NewCustomError(model.OverflowErrorType, "totalsupply", "amount exceeds total supply"))
+	}
+
+	if err := putUint64State(stub, totalSupplyKey, supply-*amount); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := putBalance(stub, from, balance-*amount); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := setEvent(stub, transferEventName, TransferEvent{From: from, To: wallet.ZeroAddress, Value: *amount}); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success(nil)
+}
@@ -0,0 +1,120 @@
+/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+func TestMintAndBurn(t *testing.T) {
+	stub := shim.NewMockStub(testChaincodeName, new(Chaincode))
+	owner, minter := newTestWallet(t), newTestWallet(t)
+	ownerAddr, minterAddr := owner.address(t, stub), minter.address(t, stub)
+	def := `{"name":"Sejong Token","symbol":"SJT","initialsupply":1000,"owner":"` + ownerAddr + `","cap":1500}`
+	if res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(def)}); res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+
+	if status, _ := owner.call(t, stub, "mint", wallet.WalletMeta{Transdata: testBob + ",100"}); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("mint without minter role = %d", status)
+	}
+	if status, msg := owner.call(t, stub, "grantRole", wallet.WalletMeta{Transdata: minterAddr + ",minter"}); status != shim.OK {
+		t.Fatal("grantRole failed", msg)
+	}
+	lastTransferEvent(t, stub)
+
+	if status, _ := minter.call(t, stub, "mint", wallet.WalletMeta{Transdata: wallet.ZeroAddress + ",1"}); status != model.StatusCode(model.AddressErrorType) {
+		t.Errorf("mint to zero address = %d", status)
+	}
+	if status, msg := minter.call(t, stub, "mint", wallet.WalletMeta{Transdata: testBob + ",400"}); status != shim.OK {
+		t.Fatal("mint failed", msg)
+	}
+	if event := lastTransferEvent(t, stub); event != (TransferEvent{From: wallet.ZeroAddress, To: testBob, Value: 400}) {
+		t.Errorf("mint event %+v", event)
+	}
+	expectQuery(t, stub, "1400", "totalSupply")
+	expectQuery(t, stub, "400", "balanceOf", testBob)
+
+	if status, _ := minter.call(t, stub, "mint", wallet.WalletMeta{Transdata: testBob + ",101"}); status != model.StatusCode(model.OverflowErrorType) {
+		t.Errorf("mint over cap = %d", status)
+	}
+	if status, msg := minter.call(t, stub, "mint", wallet.WalletMeta{Transdata: testCarol + ",100"}); status != shim.OK {
+		t.Fatal("mint up to cap failed", msg)
+	}
+	expectQuery(t, stub, "1500", "totalSupply")
+
+	if status, _ := owner.call(t, stub, "burn", wallet.WalletMeta{Transdata: "100"}); status != model.StatusCode(model.PermissionErrorType) {
+		t.Errorf("burn without burner role = %d", status)
+	}
+	if status, msg := owner.call(t, stub, "grantRole", wallet.WalletMeta{Transdata: ownerAddr + ",burner"}); status != shim.OK {
+		t.Fatal("grantRole failed", msg)
+	}
+	if status, _ := owner.call(t, stub, "burn", wallet.WalletMeta{Transdata: "1001"}); status != model.StatusCode(model.InsufficientBalanceErrorType) {
+		t.Errorf("burn over balance = %d", status)
+	}
+	lastTransferEvent(t, stub)
+	if status, msg := owner.call(t, stub, "burn", wallet.WalletMeta{Transdata: "300"}); status != shim.OK {
+		t.Fatal("burn failed", msg)
+	}
+	if event := lastTransferEvent(t, stub); event != (TransferEvent{From: ownerAddr, To: wallet.ZeroAddress, Value: 300}) {
+		t.Errorf("burn event %+v", event)
+	}
+	expectQuery(t, stub, "1200", "totalSupply")
+	expectQuery(t, stub, "700", "balanceOf", ownerAddr)
+
+	// 소각한 만큼 cap 아래로 내려가면 다시 발행할 수 있다.
+	if status, msg := minter.call(t, stub, "mint", wallet.WalletMeta{Transdata: testCarol + ",300"}); status != shim.OK {
+		t.Fatal("mint after burn failed", msg)
+	}
+	expectQuery(t, stub, "1500", "totalSupply")
+}
+
+func TestMintRejectsLegacyEnvelope(t *testing.T) {
+	stub := shim.NewMockStub(testChaincodeName, new(Chaincode))
+	owner := newTestWallet(t)
+	ownerAddr := owner.address(t, stub)
+	def := `{"name":"Sejong Token","symbol":"SJT","initialsupply":100,"owner":"` + ownerAddr + `"}`
+	if res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(def)}); res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+	for _, role := range []string{"minter", "burner"} {
+		if status, msg := owner.call(t, stub, "grantRole", wallet.WalletMeta{Transdata: ownerAddr + "," + role}); status != shim.OK {
+			t.Fatal("grantRole failed", msg)
+		}
+	}
+
+	// 기존 형식은 transdata 를 서명하지 않으므로 walletTest 봉투의 transdata 만 바꿔 재사용할 수 있다.
+	if status, _ := invoke(stub, []byte("mint"), owner.envelope(t, testBob+",100")); status != model.StatusCode(model.SignatureErrorType) {
+		t.Errorf("legacy mint = %d", status)
+	}
+	if status, _ := invoke(stub, []byte("burn"), owner.envelope(t, "100")); status != model.StatusCode(model.SignatureErrorType) {
+		t.Errorf("legacy burn = %d", status)
+	}
+	expectQuery(t, stub, "100", "totalSupply")
+}
+
+func TestMintWithoutCap(t *testing.T) {
+	stub := shim.NewMockStub(testChaincodeName, new(Chaincode))
+	owner := newTestWallet(t)
+	ownerAddr := owner.address(t, stub)
+	def := `{"name":"Sejong Token","symbol":"SJT","initialsupply":0,"owner":"` + ownerAddr + `"}`
+	if res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(def)}); res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+	if status, msg := owner.call(t, stub, "grantRole", wallet.WalletMeta{Transdata: ownerAddr + ",minter"}); status != shim.OK {
+		t.Fatal("grantRole failed", msg)
+	}
+	if status, msg := owner.call(t, stub, "mint", wallet.WalletMeta{Transdata: ownerAddr + ",18446744073709551615"}); status != shim.OK {
+		t.Fatal("mint failed", msg)
+	}
+	if status, _ := owner.call(t, stub, "mint", wallet.WalletMeta{Transdata: testBob + ",1"}); status != model.StatusCode(model.OverflowErrorType) {
+		t.Errorf("mint overflow = %d", status)
+	}
+	expectQuery(t, stub, "18446744073709551615", "totalSupply")
+}
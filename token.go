@@ -0,0 +1,676 @@
+/*
+ * SejongTelecom 코어기술개발팀
+ * @author JinSan
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/rbac"
+	"github.com/jinsan74/Erc20/utils"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+// 원장 키
+const (
+	tokenInfoKey      = "TOKEN_INFO"
+	totalSupplyKey    = "TOTAL_SUPPLY"
+	balanceKeyType    = "balance"
+	allowanceKeyType  = "allowance"
+	transferEventName = "Transfer"
+	approvalEventName = "Approval"
+)
+
+// TransferEvent is ERC-20 Transfer 이벤트
+type TransferEvent struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Value      uint64 `json:"value"`
+	Relayer    string `json:"relayer,omitempty"`    // 중계 전송이면 수수료를 받은 지갑주소
+	RelayerMSP string `json:"relayermsp,omitempty"` // 중계 전송이면 제출한 MSP ID
+	Fee        uint64 `json:"fee,omitempty"`
+}
+
+// ApprovalEvent is ERC-20 Approval 이벤트
+type ApprovalEvent struct {
+	Owner   string `json:"owner"`
+	Spender string `json:"spender"`
+	Value   uint64 `json:"value"`
+}
+
+// maxDecimals 는 uint64 잔액으로 표현 가능한 소수 자릿수 상한
+const maxDecimals = 18
+
+// maxBalanceBatch 는 balanceOfBatch 한 번에 조회할 수 있는 주소 수의 상한
+const maxBalanceBatch = 100
+
+// initToken 은 토큰 정의를 저장하고 초기 발행량을 owner 에게 발행한다.
+func (cc *Chaincode) initToken(stub shim.ChaincodeStubInterface, definition string) sc.Response {
+
+	info := model.TokenInfo{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(definition)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&info); err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.UnMarshalErrorType, "TokenInfo", err.Error()))
+	}
+
+	if info.Name == "" || info.Symbol == "" || info.Owner == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "TokenInfo", "name, symbol and owner are required"))
+	}
+	// 초기 발행량과 owner 역할을 받는 주소이므로 오타가 있으면 아무도 쓸 수 없게 된다.
+	if err := validateOwner(&info); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if info.Decimals > maxDecimals {
+		return model.ErrorResponse(model.NewCustomError(model.InitializeErrorType, "decimals", "decimals must not exceed "+strconv.Itoa(maxDecimals)))
+	}
+	if info.Cap > 0 && info.InitialSupply > info.Cap {
+		return model.ErrorResponse(model.NewCustomError(model.InitializeErrorType, "initialsupply", "initial supply exceeds cap"))
+	}
+
+	// txtime 설정은 관리자 함수로 바뀔 수 있으므로 토큰 정의와 따로 저장한다.
+	if info.TxTime != nil {
+		if err := wallet.PutTxTimeConfig(stub, *info.TxTime); err != nil {
+			return model.ErrorResponse(err)
+		}
+		info.TxTime = nil
+	}
+
+	infoBytes, err := json.Marshal(info)
+	if err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.MarshalErrorType, "TokenInfo", err.Error()))
+	}
+	if err := stub.PutState(tokenInfoKey, infoBytes); err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.PutStateErrorType, tokenInfoKey, err.Error()))
+	}
+
+	if err := putUint64State(stub, totalSupplyKey, info.InitialSupply); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := putBalance(stub, info.Owner, info.InitialSupply); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := rbac.GrantRole(stub, info.Owner, rbac.RoleOwner); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// Name is 토큰 이름 조회
+// params - 없음
+func (cc *Chaincode) Name(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	info, err := requireTokenInfo(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success([]byte(info.Name))
+}
+
+// Symbol is 토큰 심볼 조회
+// params - 없음
+func (cc *Chaincode) Symbol(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	info, err := requireTokenInfo(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success([]byte(info.Symbol))
+}
+
+// Decimals is 토큰 소수 자릿수 조회
+// params - 없음
+func (cc *Chaincode) Decimals(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	info, err := requireTokenInfo(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success([]byte(strconv.Itoa(int(info.Decimals))))
+}
+
+// TokenInfo is 토큰 정의 전체 조회 (JSON)
+// params - 없음
+func (cc *Chaincode) TokenInfo(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	infoBytes, err := stub.GetState(tokenInfoKey)
+	if err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.GetStateErrorType, tokenInfoKey, err.Error()))
+	}
+	if infoBytes == nil {
+		return model.ErrorResponse(model.NewCustomError(model.InitializeErrorType, "token", "token is not initialized"))
+	}
+	return shim.Success(infoBytes)
+}
+
+// TotalSupply is 총 발행량 조회
+// params - 없음
+func (cc *Chaincode) TotalSupply(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	supply, err := getUint64State(stub, totalSupplyKey)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success([]byte(strconv.FormatUint(supply, 10)))
+}
+
+// BalanceOf is 잔액 조회
+// params - address
+func (cc *Chaincode) BalanceOf(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 || args[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "balanceOf", "incorrect number of arguments, expecting address"))
+	}
+
+	address, err := wallet.ResolveAddress(stub, args[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	balance, err := getBalance(stub, address)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success([]byte(strconv.FormatUint(balance, 10)))
+}
+
+// BalanceOfBatch is 여러 주소의 잔액과 소수 자릿수 조회 (JSON model.BalanceBatch)
+// params - address, address, ...
+func (cc *Chaincode) BalanceOfBatch(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) == 0 || len(args) > maxBalanceBatch {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "balanceOfBatch", "expecting 1 to "+strconv.Itoa(maxBalanceBatch)+" addresses"))
+	}
+	for _, address := range args {
+		if address == "" {
+			return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "balanceOfBatch", "address is empty"))
+		}
+	}
+
+	info, err := requireTokenInfo(stub)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	addresses, err := resolveAddresses(stub, args...)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	batch := model.BalanceBatch{Decimals: info.Decimals, Balances: make([]model.BalanceEntry, len(args))}
+	for i, address := range addresses {
+		balance, err := getBalance(stub, address)
+		if err != nil {
+			return model.ErrorResponse(err)
+		}
+		batch.Balances[i] = model.BalanceEntry{Address: args[i], Balance: strconv.FormatUint(balance, 10)}
+	}
+
+	batchBytes, err := json.Marshal(batch)
+	if err != nil {
+		return model.ErrorResponse(model.NewCustomError(model.MarshalErrorType, "BalanceBatch", err.Error()))
+	}
+	return shim.Success(batchBytes)
+}
+
+// Allowance is 위임 한도 조회
+// params - owner, spender
+func (cc *Chaincode) Allowance(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 2 || args[0] == "" || args[1] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "allowance", "incorrect number of arguments, expecting owner, spender"))
+	}
+
+	addresses, err := resolveAddresses(stub, args[0], args[1])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	allowance, err := getAllowance(stub, addresses[0], addresses[1])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success([]byte(strconv.FormatUint(allowance, 10)))
+}
+
+// Transfer is 지갑형 토큰 전송
+// transjdata 에 RelayMeta 가 있으면 중계 전송으로 처리해서 전송과 함께 수수료를 relayer 에게 보낸다.
+// transdata - toaddress, amount
+// transjdata - RelayMeta (선택)
+func (cc *Chaincode) Transfer(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	params := walletParams.Params
+	if len(params) != 2 || params[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "transfer", "transdata must be toaddress,amount"))
+	}
+
+	to, err := resolveRecipient(stub, params[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	from := walletParams.Address
+	amount, err := utils.ConvertStringToUint64("amount", params[1])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	relay, err := parseRelay(stub, walletParams)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	event := TransferEvent{From: from, To: to, Value: *amount}
+	payments := []payment{{to: to, amount: *amount}}
+	spend := *amount
+	if relay != nil {
+		event.Relayer, event.RelayerMSP, event.Fee = relay.relayer, relay.relayerMSP, relay.fee
+		if relay.fee > 0 {
+			payments = append(payments, payment{to: relay.relayer, amount: relay.fee})
+			if spend+relay.fee < spend {
+				return model.ErrorResponse(model.NewCustomError(model.OverflowErrorType, "fee", "amount and fee overflow"))
+			}
+			spend += relay.fee
+		}
+	}
+
+	// 위임 키로 서명했으면 위임 사용 한도에서 차감한다.
+	if err := wallet.SpendDelegate(stub, walletParams, spend); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := payBalances(stub, from, payments...); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := setEvent(stub, transferEventName, event); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// Approve is 지갑형 위임 한도 설정
+// transdata - spender, amount
+func (cc *Chaincode) Approve(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	params := walletParams.Params
+	if len(params) != 2 || params[0] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "approve", "transdata must be spender,amount"))
+	}
+
+	spender, err := resolveRecipient(stub, params[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	owner := walletParams.Address
+	amount, err := utils.ConvertStringToUint64("amount", params[1])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	// 위임 키로 서명했으면 위임 사용 한도에서 차감한다.
+	if err := wallet.SpendDelegate(stub, walletParams, *amount); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := putAllowance(stub, owner, spender, *amount); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := setEvent(stub, approvalEventName, ApprovalEvent{Owner: owner, Spender: spender, Value: *amount}); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// TransferFrom is 지갑형 위임 전송 (서명자가 spender)
+// transdata - fromaddress, toaddress, amount
+func (cc *Chaincode) TransferFrom(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	params := walletParams.Params
+	if len(params) != 3 || params[0] == "" || params[1] == "" {
+		return model.ErrorResponse(model.NewCustomError(model.MandatoryPrameterErrorType, "transferFrom", "transdata must be fromaddress,toaddress,amount"))
+	}
+
+	to, err := resolveRecipient(stub, params[1])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	from, err := wallet.ResolveAddress(stub, params[0])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	spender := walletParams.Address
+	amount, err := utils.ConvertStringToUint64("amount", params[2])
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+
+	allowance, err := getAllowance(stub, from, spender)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	// 기존 형식 주소로 받은 위임 한도는 새 주소에 한도가 없을 때 그대로 사용한다.
+	if allowance == 0 && walletParams.LegacyAddress != "" {
+		spender = walletParams.LegacyAddress
+		if allowance, err = getAllowance(stub, from, spender); err != nil {
+			return model.ErrorResponse(err)
+		}
+	}
+	if allowance < *amount {
+		return model.ErrorResponse(model.NewCustomError(model.InsufficientAllowanceErrorType, spender, "amount exceeds allowance"))
+	}
+
+	// 위임 키로 서명했으면 위임 사용 한도에서 차감한다.
+	if err := wallet.SpendDelegate(stub, walletParams, *amount); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := moveBalance(stub, from, to, *amount); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := putAllowance(stub, from, spender, allowance-*amount); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := setEvent(stub, transferEventName, TransferEvent{From: from, To: to, Value: *amount}); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// MigrateLegacyAddress is 기존 형식 주소의 잔액과 위임 한도를 새 주소로 옮긴다.
+// 이후 기존 형식 주소로 보내거나 조회하면 새 주소로 처리된다.
+// transdata - 없음
+func (cc *Chaincode) MigrateLegacyAddress(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	walletParams, walletErr := wallet.CallVaildWallet(stub)
+	if walletErr != nil {
+		return model.ErrorResponse(walletErr)
+	}
+	linked, err := wallet.LinkLegacyAddress(stub, walletParams)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if !linked {
+		return shim.Success(nil)
+	}
+
+	legacy, address := walletParams.LegacyAddress, walletParams.Address
+	balance, err := getBalance(stub, legacy)
+	if err != nil {
+		return model.ErrorResponse(err)
+	}
+	if balance > 0 {
+		if err := moveBalance(stub, legacy, address, balance); err != nil {
+			return model.ErrorResponse(err)
+		}
+	}
+	if err := migrateAllowances(stub, legacy, address); err != nil {
+		return model.ErrorResponse(err)
+	}
+	if err := setEvent(stub, transferEventName, TransferEvent{From: legacy, To: address, Value: balance}); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success([]byte(address))
+}
+
+// migrateAllowances 는 legacy 가 owner 인 위임 한도를 address 로 옮긴다.
+// address 에 이미 같은 spender 의 한도가 있으면 그 값을 유지한다.
+func migrateAllowances(stub shim.ChaincodeStubInterface, legacy, address string) error {
+	iter, err := stub.GetStateByPartialCompositeKey(allowanceKeyType, []string{legacy})
+	if err != nil {
+		return model.NewCustomError(model.GetStatePartialCompositeKeyErrorType, allowanceKeyType, err.Error())
+	}
+	defer iter.Close()
+
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return model.NewCustomError(model.GetStatePartialCompositeKeyErrorType, allowanceKeyType, err.Error())
+		}
+		_, keys, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil || len(keys) != 2 {
+			return model.NewCustomError(model.SpliteCompositeKeyErrorType, kv.Key, "invalid allowance key")
+		}
+		spender := keys[1]
+
+		current, err := getAllowance(stub, address, spender)
+		if err != nil {
+			return err
+		}
+		if current == 0 {
+			allowance, err := strconv.ParseUint(string(kv.Value), 10, 64)
+			if err != nil {
+				return model.NewCustomError(model.ConvertErrorType, kv.Key, err.Error())
+			}
+			if err := putAllowance(stub, address, spender, allowance); err != nil {
+				return err
+			}
+		}
+		if err := stub.DelState(kv.Key); err != nil {
+			return model.NewCustomError(model.PutStateErrorType, kv.Key, err.Error())
+		}
+	}
+	return nil
+}
+
+// resolveAddresses 는 기존 형식 주소를 매핑된 새 주소로 바꾼다.
+func resolveAddresses(stub shim.ChaincodeStubInterface, addresses ...string) ([]string, error) {
+	resolved := make([]string, len(addresses))
+	for i, address := range addresses {
+		var err error
+		if resolved[i], err = wallet.ResolveAddress(stub, address); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// resolveRecipient 는 받는 주소를 새 주소로 바꾸고 올바른 지갑주소인지 확인한다.
+// 매핑되지 않은 기존 형식 주소는 체크섬이 없어 오타와 구분할 수 없으므로 받지 않는다.
+func resolveRecipient(stub shim.ChaincodeStubInterface, address string) (string, error) {
+	resolved, err := wallet.ResolveAddress(stub, address)
+	if err != nil {
+		return "", err
+	}
+	if err := wallet.ValidateAddress(resolved); err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// validateOwner 는 토큰 정의의 owner 가 지갑주소인지 확인한다.
+// 기존 형식 주소는 체크섬이 없으므로 legacyowner 로 명시한 경우에만 받는다.
+func validateOwner(info *model.TokenInfo) error {
+	if info.LegacyOwner {
+		return wallet.ValidateLegacyAddress(info.Owner)
+	}
+	return wallet.ValidateAddress(info.Owner)
+}
+
+// getTokenInfo 는 초기화 전이면 nil 을 반환한다.
+func getTokenInfo(stub shim.ChaincodeStubInterface) (*model.TokenInfo, error) {
+	infoBytes, err := stub.GetState(tokenInfoKey)
+	if err != nil {
+		return nil, model.NewCustomError(model.GetStateErrorType, tokenInfoKey, err.Error())
+	}
+	if infoBytes == nil {
+		return nil, nil
+	}
+	info := model.TokenInfo{}
+	if err := json.Unmarshal(infoBytes, &info); err != nil {
+		return nil, model.NewCustomError(model.UnMarshalErrorType, tokenInfoKey, err.Error())
+	}
+	return &info, nil
+}
+
+func requireTokenInfo(stub shim.ChaincodeStubInterface) (*model.TokenInfo, error) {
+	info, err := getTokenInfo(stub)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, model.NewCustomError(model.InitializeErrorType, "token", "token is not initialized")
+	}
+	return info, nil
+}
+
+// moveBalance 는 from 에서 to 로 잔액을 옮긴다.
+// Fabric 의 GetState 는 같은 트랜잭션의 PutState 를 보지 못하므로 from == to 는 잔액 확인만 한다.
+func moveBalance(stub shim.ChaincodeStubInterface, from, to string, amount uint64) error {
+	return payBalances(stub, from, payment{to: to, amount: amount})
+}
+
+// payment is payBalances 로 보낼 받는 주소와 금액
+type payment struct {
+	to     string
+	amount uint64
+}
+
+// payBalances 는 from 에서 payments 를 한 번에 보낸다.
+// Fabric 은 같은 트랜잭션에서 쓴 값을 GetState 로 읽을 수 없으므로 주소마다 잔액을 한 번만 읽고 한 번만 쓴다.
+func payBalances(stub shim.ChaincodeStubInterface, from string, payments ...payment) error {
+
+	original, balances := map[string]uint64{}, map[string]uint64{}
+	addresses := []string{}
+	balanceOf := func(address string) (uint64, error) {
+		if balance, ok := balances[address]; ok {
+			return balance, nil
+		}
+		balance, err := getBalance(stub, address)
+		if err != nil {
+			return 0, err
+		}
+		original[address], balances[address] = balance, balance
+		addresses = append(addresses, address)
+		return balance, nil
+	}
+
+	var total uint64
+	for _, p := range payments {
+		if total+p.amount < total {
+			return model.NewCustomError(model.OverflowErrorType, from, "amount overflow")
+		}
+		total += p.amount
+	}
+	fromBalance, err := balanceOf(from)
+	if err != nil {
+		return err
+	}
+	if fromBalance < total {
+		return model.NewCustomError(model.InsufficientBalanceErrorType, from, "amount exceeds balance")
+	}
+	balances[from] = fromBalance - total
+
+	for _, p := range payments {
+		toBalance, err := balanceOf(p.to)
+		if err != nil {
+			return err
+		}
+		if toBalance+p.amount < toBalance {
+			return model.NewCustomError(model.OverflowErrorType, p.to, "balance overflow")
+		}
+		balances[p.to] = toBalance + p.amount
+	}
+
+	for _, address := range addresses {
+		if balances[address] == original[address] {
+			continue
+		}
+		if err := putBalance(stub, address, balances[address]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func balanceKey(stub shim.ChaincodeStubInterface, address string) (string, error) {
+	key, err := stub.CreateCompositeKey(balanceKeyType, []string{address})
+	if err != nil {
+		return "", model.NewCustomError(model.CreateCompositeKeyErrorType, balanceKeyType, err.Error())
+	}
+	return key, nil
+}
+
+func allowanceKey(stub shim.ChaincodeStubInterface, owner, spender string) (string, error) {
+	key, err := stub.CreateCompositeKey(allowanceKeyType, []string{owner, spender})
+	if err != nil {
+		return "", model.NewCustomError(model.CreateCompositeKeyErrorType, allowanceKeyType, err.Error())
+	}
+	return key, nil
+}
+
+func getBalance(stub shim.ChaincodeStubInterface, address string) (uint64, error) {
+	key, err := balanceKey(stub, address)
+	if err != nil {
+		return 0, err
+	}
+	return getUint64State(stub, key)
+}
+
+func putBalance(stub shim.ChaincodeStubInterface, address string, balance uint64) error {
+	key, err := balanceKey(stub, address)
+	if err != nil {
+		return err
+	}
+	return putUint64State(stub, key, balance)
+}
+
+func getAllowance(stub shim.ChaincodeStubInterface, owner, spender string) (uint64, error) {
+	key, err := allowanceKey(stub, owner, spender)
+	if err != nil {
+		return 0, err
+	}
+	return getUint64State(stub, key)
+}
+
+func putAllowance(stub shim.ChaincodeStubInterface, owner, spender string, allowance uint64) error {
+	key, err := allowanceKey(stub, owner, spender)
+	if err != nil {
+		return err
+	}
+	return putUint64State(stub, key, allowance)
+}
+
+// getUint64State 는 값이 없으면 0 을 반환한다.
+func getUint64State(stub shim.ChaincodeStubInterface, key string) (uint64, error) {
+	value, err := stub.GetState(key)
+	if err != nil {
+		return 0, model.NewCustomError(model.GetStateErrorType, key, err.Error())
+	}
+	if value == nil {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return 0, model.NewCustomError(model.ConvertErrorType, key, err.Error())
+	}
+	return n, nil
+}
+
+func putUint64State(stub shim.ChaincodeStubInterface, key string, value uint64) error {
+	if err := stub.PutState(key, []byte(strconv.FormatUint(value, 10))); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	return nil
+}
+
+func setEvent(stub shim.ChaincodeStubInterface, name string, event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return model.NewCustomError(model.MarshalErrorType, name, err.Error())
+	}
+	if err := stub.SetEvent(name, payload); err != nil {
+		return model.NewCustomError(model.SetEventErrorType, name, err.Error())
+	}
+	return nil
+}
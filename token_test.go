@@ -0,0 +1,752 @@
+/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+// 고정 받는 주소 (wallet 패키지 테스트 벡터의 P-256, secp256k1 주소)
+const (
+	testBob   = "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg"
+	testCarol = "uEHhePkzGgQmsAndX9MQTvviBMGNdF3YP9"
+)
+
+// testChaincodeName 은 canonical 서명 테스트의 토큰 체인코드 이름 (MockStub 이름)
+const testChaincodeName = "erc20"
+
+// TestMain 은 peer 가 체인코드 프로세스에 넘겨주는 체인코드 ID 를 설정한다.
+func TestMain(m *testing.M) {
+	os.Setenv("CORE_CHAINCODE_ID_NAME", testChaincodeName+":1.0")
+	os.Exit(m.Run())
+}
+
+// testWallet 은 테스트용 ECDSA 지갑
+type testWallet struct {
+	key   *ecdsa.PrivateKey
+	pub   string
+	curve string
+}
+
+func newTestWallet(t *testing.T) *testWallet {
+	return newCurveTestWallet(t, "", elliptic.P256())
+}
+
+func newCurveTestWallet(t *testing.T, curveID string, curve elliptic.Curve) *testWallet {
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testWallet{key: key, pub: fmt.Sprintf("%064X:%064X", key.X, key.Y), curve: curveID}
+}
+
+// envelope 은 transdata 를 담은 기존 방식(sigver 1) 서명 WalletMeta JSON 을 만든다.
+func (w *testWallet) envelope(t *testing.T, transdata string) []byte {
+	return w.sign(t, wallet.WalletMeta{Transdata: transdata}, wallet.SigContext{})
+}
+
+// sign 은 meta 의 Sigver 에 맞춰 서명한 WalletMeta JSON 을 만든다.
+func (w *testWallet) sign(t *testing.T, meta wallet.WalletMeta, ctx wallet.SigContext) []byte {
+	meta.Publickey = w.pub
+	meta.Curve = w.curve
+	if meta.Txtime == "" {
+		meta.Txtime = strconv.FormatInt(time.Now().Unix(), 10)
+	}
+
+	msg := meta.Publickey + meta.Txtime
+	if meta.Sigver == wallet.SigVerCanonical || meta.Sigver == wallet.SigVerSHA256 {
+		msg = wallet.CanonicalMessage(meta, directCall(ctx))
+	}
+	meta.Sigmsg = w.signMessage(t, msg, meta.Sigver)
+
+	metaBytes, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metaBytes
+}
+
+// directCall 은 실행 함수와 체인코드를 지정하지 않은 ctx 를 proposal 의 함수와 체인코드를 직접 호출한 것으로 채운다.
+func directCall(ctx wallet.SigContext) wallet.SigContext {
+	if ctx.ExecFunction == "" && ctx.ExecChaincode == "" {
+		ctx.ExecFunction, ctx.ExecChaincode = ctx.Function, ctx.Chaincode
+	}
+	return ctx
+}
+
+// signMessage 는 Sigver 의 digest 로 msg 에 서명한 hex DER 서명을 만든다.
+func (w *testWallet) signMessage(t *testing.T, msg string, sigver string) string {
+	msgHash := sha256.Sum256([]byte(msg))
+	digest := msgHash[:]
+	if sigver != wallet.SigVerSHA256 {
+		sha1Digest := sha1.Sum([]byte(fmt.Sprintf("%x", msgHash)))
+		digest = sha1Digest[:]
+	}
+
+	r, s, err := ecdsa.Sign(rand.Reader, w.key, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// canonical 서명은 low-S 만 받는다.
+	if n := w.key.Curve.Params().N; s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s.Sub(n, s)
+	}
+	sig, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(sig)
+}
+
+// signedProposal 은 클라이언트가 chaincode 의 args 를 호출하는 SignedProposal 을 만든다.
+func signedProposal(t *testing.T, chaincode string, args [][]byte) *pb.SignedProposal {
+	cis, err := proto.Marshal(&pb.ChaincodeInvocationSpec{ChaincodeSpec: &pb.ChaincodeSpec{
+		ChaincodeId: &pb.ChaincodeID{Name: chaincode},
+		Input:       &pb.ChaincodeInput{Args: args},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := proto.Marshal(&pb.ChaincodeProposalPayload{Input: cis})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prop, err := proto.Marshal(&pb.Proposal{Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pb.SignedProposal{ProposalBytes: prop}
+}
+
+// invokeSigned 는 stub 이름의 체인코드를 직접 호출한 proposal 로 실행한다.
+func invokeSigned(t *testing.T, stub *shim.MockStub, args ...[]byte) (int32, string) {
+	res := stub.MockInvokeWithSignedProposal("tx", args, signedProposal(t, stub.Name, args))
+	if res.Status != shim.OK {
+		return res.Status, res.Message
+	}
+	return res.Status, string(res.Payload)
+}
+
+// call 은 stub 의 function 을 직접 호출하는 canonical 서명 봉투로 실행한다. Sigver 가 비어 있으면 sigver 3 으로 서명한다.
+func (w *testWallet) call(t *testing.T, stub *shim.MockStub, function string, meta wallet.WalletMeta) (int32, string) {
+	if meta.Sigver == "" {
+		meta.Sigver = wallet.SigVerSHA256
+	}
+	ctx := wallet.SigContext{Function: function, Channel: stub.ChannelID, Chaincode: stub.Name}
+	return invokeSigned(t, stub, []byte(function), w.sign(t, meta, ctx))
+}
+
+// address 는 walletTest 로 지갑주소를 얻는다.
+func (w *testWallet) address(t *testing.T, stub *shim.MockStub) string {
+	res := stub.MockInvoke("addr", [][]byte{[]byte("walletTest"), w.envelope(t, "")})
+	if res.Status != shim.OK {
+		t.Fatal("walletTest failed", res.Message)
+	}
+	return string(res.Payload)
+}
+
+func seedBalance(t *testing.T, stub *shim.MockStub, address string, amount uint64) {
+	stub.MockTransactionStart("seed")
+	defer stub.MockTransactionEnd("seed")
+	if err := putBalance(stub, address, amount); err != nil {
+		t.Fatal(err)
+	}
+	if err := putUint64State(stub, totalSupplyKey, amount); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func invoke(stub *shim.MockStub, args ...[]byte) (int32, string) {
+	res := stub.MockInvoke("tx", args)
+	if res.Status != shim.OK {
+		return res.Status, res.Message
+	}
+	return res.Status, string(res.Payload)
+}
+
+func expectQuery(t *testing.T, stub *shim.MockStub, want string, args ...string) {
+	t.Helper()
+	bargs := make([][]byte, len(args))
+	for i, arg := range args {
+		bargs[i] = []byte(arg)
+	}
+	status, got := invoke(stub, bargs...)
+	if status != shim.OK || got != want {
+		t.Errorf("%v = %d %q, want %q", args, status, got, want)
+	}
+}
+
+func TestTokenMetadata(t *testing.T) {
+	stub := shim.NewMockStub("chaincode", new(Chaincode))
+	def := `{"name":"Sejong Token","symbol":"SJT","decimals":8,"initialsupply":1000,"owner":"` + testCarol + `","cap":5000}`
+	if res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(def)}); res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+
+	expectQuery(t, stub, "Sejong Token", "name")
+	expectQuery(t, stub, "SJT", "symbol")
+	expectQuery(t, stub, "8", "decimals")
+	expectQuery(t, stub, "1000", "totalSupply")
+	expectQuery(t, stub, "1000", "balanceOf", testCarol)
+	expectQuery(t, stub, def, "tokenInfo")
+}
+
+func TestBalanceOfBatch(t *testing.T) {
+	stub := shim.NewMockStub("chaincode", new(Chaincode))
+	def := `{"name":"Sejong Token","symbol":"SJT","decimals":8,"initialsupply":1000,"owner":"` + testCarol + `"}`
+	if res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(def)}); res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+
+	want := `{"decimals":8,"balances":[{"address":"` + testCarol + `","balance":"1000"},{"address":"` + testBob + `","balance":"0"}]}`
+	expectQuery(t, stub, want, "balanceOfBatch", testCarol, testBob)
+
+	if status, _ := invoke(stub, []byte("balanceOfBatch")); status != model.StatusCode(model.MandatoryPrameterErrorType) {
+		t.Errorf("balanceOfBatch without addresses = %d", status)
+	}
+	if status, _ := invoke(stub, []byte("balanceOfBatch"), []byte(testBob), []byte("")); status != model.StatusCode(model.MandatoryPrameterErrorType) {
+		t.Errorf("balanceOfBatch with empty address = %d", status)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	stub := shim.NewMockStub("chaincode", new(Chaincode))
+	alice, bob := newTestWallet(t), newTestWallet(t)
+	aliceAddr, bobAddr := alice.address(t, stub), bob.address(t, stub)
+	seedBalance(t, stub, aliceAddr, 100)
+
+	if status, msg := invoke(stub, []byte("transfer"), alice.envelope(t, bobAddr+",30")); status != shim.OK {
+		t.Fatal("transfer failed", msg)
+	}
+	expectQuery(t, stub, "70", "balanceOf", aliceAddr)
+	expectQuery(t, stub, "30", "balanceOf", bobAddr)
+	expectQuery(t, stub, "100", "totalSupply")
+
+	if status, _ := invoke(stub, []byte("transfer"), alice.envelope(t, bobAddr+",71")); status == shim.OK {
+		t.Error("transfer above balance succeeded")
+	}
+	if status, _ := invoke(stub, []byte("transfer"), alice.envelope(t, aliceAddr+",70")); status != shim.OK {
+		t.Error("self transfer failed")
+	}
+	expectQuery(t, stub, "70", "balanceOf", aliceAddr)
+}
+
+func TestTransferRejectsBadSignature(t *testing.T) {
+	stub := shim.NewMockStub("chaincode", new(Chaincode))
+	alice, mallory := newTestWallet(t), newTestWallet(t)
+	aliceAddr := alice.address(t, stub)
+	seedBalance(t, stub, aliceAddr, 100)
+
+	meta, other := map[string]string{}, map[string]string{}
+	json.Unmarshal(alice.envelope(t, "someone,10"), &meta)
+	json.Unmarshal(mallory.envelope(t, "someone,10"), &other)
+	meta["sigmsg"] = other["sigmsg"]
+	forged, _ := json.Marshal(meta)
+
+	if status, _ := invoke(stub, []byte("transfer"), forged); status != model.StatusCode(model.SignatureErrorType) {
+		t.Error("transfer with forged signature", status)
+	}
+	expectQuery(t, stub, "100", "balanceOf", aliceAddr)
+}
+
+func TestCanonicalSignatureCoversTransdata(t *testing.T) {
+	stub := shim.NewMockStub("erc20", new(Chaincode))
+	stub.ChannelID = "mychannel"
+	alice := newTestWallet(t)
+	aliceAddr := alice.address(t, stub)
+	seedBalance(t, stub, aliceAddr, 100)
+
+	ctx := wallet.SigContext{Function: "transfer", Channel: "mychannel", Chaincode: "erc20"}
+	signed := alice.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerCanonical, Transdata: testBob + ",10"}, ctx)
+	if status, msg := invokeSigned(t, stub, []byte("transfer"), signed); status != shim.OK {
+		t.Fatal("canonical transfer failed", msg)
+	}
+	expectQuery(t, stub, "10", "balanceOf", testBob)
+
+	// 수신자나 금액을 바꾸면 서명이 맞지 않는다.
+	meta := wallet.WalletMeta{}
+	json.Unmarshal(signed, &meta)
+	meta.Transdata = "mallory,10"
+	tampered, _ := json.Marshal(meta)
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), tampered); status == shim.OK {
+		t.Error("transfer with tampered transdata succeeded")
+	}
+
+	// 다른 함수나 체인코드를 위한 서명은 재사용할 수 없다.
+	approve := alice.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerCanonical, Transdata: testBob + ",10"}, wallet.SigContext{Function: "approve", Channel: "mychannel", Chaincode: "erc20"})
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), approve); status == shim.OK {
+		t.Error("transfer with approve signature succeeded")
+	}
+	other := alice.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerCanonical, Transdata: testBob + ",10"}, wallet.SigContext{Function: "transfer", Channel: "mychannel", Chaincode: "other"})
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), other); status == shim.OK {
+		t.Error("transfer with other chaincode signature succeeded")
+	}
+	expectQuery(t, stub, "90", "balanceOf", aliceAddr)
+}
+
+// routerChaincode 는 받은 지갑 파라미터를 바꾸지 않고 토큰 체인코드의 function 으로 전달한다.
+type routerChaincode struct {
+	function string
+}
+
+func (r *routerChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (r *routerChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	_, params := stub.GetFunctionAndParameters()
+	return stub.InvokeChaincode(testChaincodeName, [][]byte{[]byte(r.function), []byte(params[0])}, stub.GetChannelID())
+}
+
+// forwardedChaincode 는 MockStub 이 전달하지 않는 호출한 체인코드의 signed proposal 로 Chaincode 를 실행한다.
+type forwardedChaincode struct {
+	*Chaincode
+	caller *shim.MockStub
+}
+
+type proposalStub struct {
+	shim.ChaincodeStubInterface
+	prop *pb.SignedProposal
+}
+
+func (s *proposalStub) GetSignedProposal() (*pb.SignedProposal, error) { return s.prop, nil }
+
+func (cc *forwardedChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	prop, _ := cc.caller.GetSignedProposal()
+	return cc.Chaincode.Invoke(&proposalStub{ChaincodeStubInterface: stub, prop: prop})
+}
+
+func TestForwardedSignatureCoversExecutingFunction(t *testing.T) {
+	router := &routerChaincode{}
+	routerStub := shim.NewMockStub("router", router)
+	routerStub.ChannelID = "mychannel"
+	stub := shim.NewMockStub(testChaincodeName, &forwardedChaincode{Chaincode: new(Chaincode), caller: routerStub})
+	stub.ChannelID = "mychannel"
+	routerStub.MockPeerChaincode(testChaincodeName+"/mychannel", stub)
+
+	alice := newTestWallet(t)
+	aliceAddr := alice.address(t, stub)
+	seedBalance(t, stub, aliceAddr, 100)
+
+	ctx := wallet.SigContext{Function: "pay", Channel: "mychannel", Chaincode: "router", ExecFunction: "transfer", ExecChaincode: testChaincodeName}
+	signed := alice.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerSHA256, Transdata: testBob + ",10"}, ctx)
+
+	// transfer 로 서명한 봉투를 approve 로 바꿔 전달하면 서명이 맞지 않는다.
+	router.function = "approve"
+	if status, _ := invokeSigned(t, routerStub, []byte("pay"), signed); status != model.StatusCode(model.SignatureErrorType) {
+		t.Errorf("forwarded approve = %d", status)
+	}
+	expectQuery(t, stub, "0", "allowance", aliceAddr, testBob)
+
+	router.function = "transfer"
+	if status, msg := invokeSigned(t, routerStub, []byte("pay"), signed); status != shim.OK {
+		t.Fatal("forwarded transfer failed", msg)
+	}
+	expectQuery(t, stub, "10", "balanceOf", testBob)
+
+	// 토큰을 직접 호출하는 서명은 다른 체인코드를 거쳐 전달할 수 없다.
+	direct := alice.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerSHA256, Transdata: testBob + ",10"}, wallet.SigContext{Function: "transfer", Channel: "mychannel", Chaincode: testChaincodeName})
+	if status, _ := invokeSigned(t, routerStub, []byte("pay"), direct); status != model.StatusCode(model.SignatureErrorType) {
+		t.Errorf("forwarded direct signature = %d", status)
+	}
+	expectQuery(t, stub, "90", "balanceOf", aliceAddr)
+}
+
+func TestVerifySigner(t *testing.T) {
+	router := &routerChaincode{function: "verifySigner"}
+	routerStub := shim.NewMockStub("fund", router)
+	routerStub.ChannelID = "mychannel"
+	stub := shim.NewMockStub(testChaincodeName, &forwardedChaincode{Chaincode: new(Chaincode), caller: routerStub})
+	stub.ChannelID = "mychannel"
+	routerStub.MockPeerChaincode(testChaincodeName+"/mychannel", stub)
+
+	alice := newTestWallet(t)
+	aliceAddr := alice.address(t, stub)
+	ctx := wallet.SigContext{Function: "registerFund", Channel: "mychannel", Chaincode: "fund", ExecFunction: "verifySigner", ExecChaincode: testChaincodeName}
+
+	signed := alice.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerSHA256, Nonce: "1", Transdata: "F1"}, ctx)
+	status, payload := invokeSigned(t, routerStub, []byte("registerFund"), signed)
+	signer := wallet.Signer{}
+	if status != shim.OK || json.Unmarshal([]byte(payload), &signer) != nil || signer.Address != aliceAddr || signer.KeyAddress != aliceAddr {
+		t.Fatalf("verifySigner = %d %s", status, payload)
+	}
+	// 서명자 확인도 토큰 체인코드의 nonce 를 소모한다.
+	expectQuery(t, stub, "2", "getNonce", aliceAddr)
+	if status, _ := invokeSigned(t, routerStub, []byte("registerFund"), signed); status != model.StatusCode(model.NonceErrorType) {
+		t.Errorf("replayed verifySigner = %d", status)
+	}
+
+	legacy := alice.sign(t, wallet.WalletMeta{Transdata: "F1"}, ctx)
+	if status, _ := invokeSigned(t, routerStub, []byte("registerFund"), legacy); status != model.StatusCode(model.SignatureErrorType) {
+		t.Errorf("legacy verifySigner = %d", status)
+	}
+}
+
+func TestNonceReplayProtection(t *testing.T) {
+	stub := shim.NewMockStub("erc20", new(Chaincode))
+	alice := newTestWallet(t)
+	aliceAddr := alice.address(t, stub)
+	seedBalance(t, stub, aliceAddr, 100)
+	ctx := wallet.SigContext{Function: "transfer", Chaincode: "erc20"}
+
+	expectQuery(t, stub, "1", "getNonce", aliceAddr)
+	signed := alice.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerCanonical, Nonce: "1", Transdata: testBob + ",10"}, ctx)
+	if status, msg := invokeSigned(t, stub, []byte("transfer"), signed); status != shim.OK {
+		t.Fatal("transfer with nonce failed", msg)
+	}
+	expectQuery(t, stub, "2", "getNonce", aliceAddr)
+
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), signed); status == shim.OK {
+		t.Error("replayed envelope succeeded")
+	}
+	stale := alice.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerCanonical, Nonce: "1", Transdata: testBob + ",10"}, ctx)
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), stale); status == shim.OK {
+		t.Error("duplicate nonce succeeded")
+	}
+
+	// 건너뛴 nonce 나 표준 표기가 아닌 nonce 는 받지 않는다.
+	for _, nonce := range []string{"5", "18446744073709551615", "02", "+2"} {
+		skipped := alice.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerCanonical, Nonce: nonce, Transdata: testBob + ",10"}, ctx)
+		if status, _ := invokeSigned(t, stub, []byte("transfer"), skipped); status != model.StatusCode(model.NonceErrorType) {
+			t.Errorf("nonce %s = %d", nonce, status)
+		}
+	}
+	expectQuery(t, stub, "2", "getNonce", aliceAddr)
+
+	next := alice.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerCanonical, Nonce: "2", Transdata: testBob + ",10"}, ctx)
+	if status, msg := invokeSigned(t, stub, []byte("transfer"), next); status != shim.OK {
+		t.Fatal("transfer with next nonce failed", msg)
+	}
+	expectQuery(t, stub, "3", "getNonce", aliceAddr)
+	expectQuery(t, stub, "20", "balanceOf", testBob)
+
+	// 기존 방식은 nonce 를 서명하지 않으므로 거부한다.
+	legacy := alice.sign(t, wallet.WalletMeta{Nonce: "3", Transdata: testBob + ",10"}, ctx)
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), legacy); status == shim.OK {
+		t.Error("legacy envelope with nonce succeeded")
+	}
+}
+
+func TestTxTimeWindow(t *testing.T) {
+	stub := shim.NewMockStub("erc20", new(Chaincode))
+	owner, alice := newTestWallet(t), newTestWallet(t)
+	ownerAddr := owner.address(t, stub)
+	def := `{"name":"Sejong Token","symbol":"SJT","initialsupply":100,"owner":"` + ownerAddr + `","txtime":{"pastsec":30,"futuresec":5}}`
+	if res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(def)}); res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+
+	now := time.Now().Unix()
+	for _, tc := range []struct {
+		offset int64
+		ok     bool
+	}{
+		{-20, true},
+		{-60, false},
+		{3, true},
+		{20, false},
+	} {
+		signed := owner.sign(t, wallet.WalletMeta{Txtime: strconv.FormatInt(now+tc.offset, 10), Transdata: testBob + ",1"}, wallet.SigContext{})
+		if status, msg := invoke(stub, []byte("transfer"), signed); (status == shim.OK) != tc.ok {
+			t.Errorf("txtime offset %d: status %d %s, want ok=%v", tc.offset, status, msg, tc.ok)
+		}
+	}
+
+	// owner 만 범위를 바꿀 수 있다.
+	disable := `{"pastsec":0,"futuresec":0,"disabled":true}`
+	if status, _ := alice.call(t, stub, "setTxTimeWindow", wallet.WalletMeta{Transjdata: disable}); status == shim.OK {
+		t.Error("non-owner changed tx time window")
+	}
+	if status, msg := owner.call(t, stub, "setTxTimeWindow", wallet.WalletMeta{Transjdata: disable}); status != shim.OK {
+		t.Fatal("setTxTimeWindow failed", msg)
+	}
+	old := owner.sign(t, wallet.WalletMeta{Txtime: strconv.FormatInt(now-3600, 10), Transdata: testBob + ",1"}, wallet.SigContext{})
+	if status, msg := invoke(stub, []byte("transfer"), old); status != shim.OK {
+		t.Error("transfer with disabled window failed", msg)
+	}
+}
+
+func TestSecp256k1Wallet(t *testing.T) {
+	stub := shim.NewMockStub("erc20", new(Chaincode))
+	p256, k1 := newTestWallet(t), newCurveTestWallet(t, wallet.CurveSecp256k1, secp256k1.S256())
+	ctx := wallet.SigContext{Function: "walletTest", Chaincode: "erc20"}
+
+	status, k1Addr := invokeSigned(t, stub, []byte("walletTest"), k1.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerCanonical}, ctx))
+	if status != shim.OK {
+		t.Fatal("secp256k1 walletTest failed", k1Addr)
+	}
+	if p256Addr := p256.address(t, stub); k1Addr == p256Addr {
+		t.Errorf("secp256k1 address %s collides with legacy address", k1Addr)
+	}
+
+	seedBalance(t, stub, k1Addr, 100)
+	signed := k1.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerCanonical, Transdata: testBob + ",40"}, wallet.SigContext{Function: "transfer", Chaincode: "erc20"})
+	if status, msg := invokeSigned(t, stub, []byte("transfer"), signed); status != shim.OK {
+		t.Fatal("secp256k1 transfer failed", msg)
+	}
+	expectQuery(t, stub, "60", "balanceOf", k1Addr)
+
+	// 새 곡선은 기존 서명 방식을 받지 않는다.
+	if status, _ := invoke(stub, []byte("transfer"), k1.envelope(t, testBob+",1")); status == shim.OK {
+		t.Error("secp256k1 legacy signature succeeded")
+	}
+	// P-256 키를 secp256k1 로 주장하면 곡선 위의 점이 아니다.
+	wrongCurve := &testWallet{key: p256.key, pub: p256.pub, curve: wallet.CurveSecp256k1}
+	if status, _ := invokeSigned(t, stub, []byte("walletTest"), wrongCurve.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerCanonical}, ctx)); status != model.StatusCode(model.CurvePointErrorType) {
+		t.Error("p256 key accepted as secp256k1", status)
+	}
+}
+
+func TestSHA256SigverAndDisableSHA1(t *testing.T) {
+	stub := shim.NewMockStub("erc20", new(Chaincode))
+	owner, alice := newTestWallet(t), newTestWallet(t)
+	ownerAddr := owner.address(t, stub)
+	def := `{"name":"Sejong Token","symbol":"SJT","initialsupply":100,"owner":"` + ownerAddr + `"}`
+	if res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(def)}); res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+	transferCtx := wallet.SigContext{Function: "transfer", Chaincode: "erc20"}
+
+	v3 := owner.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerSHA256, Transdata: testBob + ",1"}, transferCtx)
+	if status, msg := invokeSigned(t, stub, []byte("transfer"), v3); status != shim.OK {
+		t.Fatal("sigver 3 transfer failed", msg)
+	}
+
+	disable := wallet.WalletMeta{Transjdata: `{"disablesha1":true}`}
+	if status, _ := alice.call(t, stub, "setSigConfig", disable); status == shim.OK {
+		t.Error("non-owner changed signature config")
+	}
+	if status, msg := owner.call(t, stub, "setSigConfig", disable); status != shim.OK {
+		t.Fatal("setSigConfig failed", msg)
+	}
+
+	for _, sigver := range []string{wallet.SigVerLegacy, wallet.SigVerCanonical} {
+		signed := owner.sign(t, wallet.WalletMeta{Sigver: sigver, Transdata: testBob + ",1"}, transferCtx)
+		if status, _ := invokeSigned(t, stub, []byte("transfer"), signed); status == shim.OK {
+			t.Errorf("sigver %s transfer succeeded with SHA-1 disabled", sigver)
+		}
+	}
+	v3 = owner.sign(t, wallet.WalletMeta{Sigver: wallet.SigVerSHA256, Transdata: testBob + ",1"}, transferCtx)
+	if status, msg := invokeSigned(t, stub, []byte("transfer"), v3); status != shim.OK {
+		t.Fatal("sigver 3 transfer failed", msg)
+	}
+	expectQuery(t, stub, "2", "balanceOf", testBob)
+}
+
+func TestApproveAndTransferFrom(t *testing.T) {
+	stub := shim.NewMockStub("chaincode", new(Chaincode))
+	alice, bob := newTestWallet(t), newTestWallet(t)
+	aliceAddr, bobAddr := alice.address(t, stub), bob.address(t, stub)
+	seedBalance(t, stub, aliceAddr, 100)
+
+	if status, msg := invoke(stub, []byte("approve"), alice.envelope(t, bobAddr+",50")); status != shim.OK {
+		t.Fatal("approve failed", msg)
+	}
+	expectQuery(t, stub, "50", "allowance", aliceAddr, bobAddr)
+
+	if status, msg := invoke(stub, []byte("transferFrom"), bob.envelope(t, aliceAddr+","+testCarol+",20")); status != shim.OK {
+		t.Fatal("transferFrom failed", msg)
+	}
+	expectQuery(t, stub, "30", "allowance", aliceAddr, bobAddr)
+	expectQuery(t, stub, "80", "balanceOf", aliceAddr)
+	expectQuery(t, stub, "20", "balanceOf", testCarol)
+
+	if status, _ := invoke(stub, []byte("transferFrom"), bob.envelope(t, aliceAddr+","+testCarol+",31")); status == shim.OK {
+		t.Error("transferFrom above allowance succeeded")
+	}
+	if status, _ := invoke(stub, []byte("transferFrom"), alice.envelope(t, aliceAddr+","+testCarol+",1")); status == shim.OK {
+		t.Error("transferFrom without allowance succeeded")
+	}
+}
+
+func TestMigrateLegacyAddress(t *testing.T) {
+	const (
+		legacyAddr = "BV8oLGiPd8qTGsEqqJ7b9V7X29VuQcea2C"
+		newAddr    = "tcccaeH7kVa9qZQANJt7fC7gxNj3ARTPKk"
+		// 기존 형식 주소를 가진 고정 서명 봉투(2020-12-28), 기존 방식은 transdata 를 서명하지 않는다.
+		legacyEnvelope = `{"publickey":"00B413D9FAD1FC5B50CB93B9B7C554CE5B3A449115AA361B24B46D9156E0512E25:00E9FC5AAAF269E7EB0BD5003DC3C9F7FF159522FE62E2F23E7F717481809044A5","txtime":"1609128127","sigmsg":"3045022100A06221FFA0C44A0A12051A1C8694D4BA475C3D121D069D6EA6AF0F7576426E9A02206DECA4CCB32A0A86913281DE0C05A27DAF16E82F894E6D34E6E1488CE4AE5B8F"}`
+	)
+
+	stub := shim.NewMockStub("chaincode", new(Chaincode))
+	def := `{"name":"Sejong Token","symbol":"SJT","initialsupply":1000,"owner":"` + legacyAddr + `","legacyowner":true,"txtime":{"disabled":true}}`
+	if res := stub.MockInit("1", [][]byte{[]byte("init"), []byte(def)}); res.Status != shim.OK {
+		t.Fatal("Init failed", res.Message)
+	}
+	stub.MockTransactionStart("seed")
+	if err := putAllowance(stub, legacyAddr, testCarol, 50); err != nil {
+		t.Fatal(err)
+	}
+	stub.MockTransactionEnd("seed")
+
+	expectQuery(t, stub, "1000", "balanceOf", legacyAddr)
+	expectQuery(t, stub, "0", "balanceOf", newAddr)
+
+	// 기존 방식 봉투는 transjdata 를 서명하지 않으므로 owner 의 봉투라도 관리자 함수에 쓸 수 없다.
+	sigConfigEnvelope := strings.Replace(legacyEnvelope, `"txtime"`, `"transjdata":"{}","txtime"`, 1)
+	if status, _ := invoke(stub, []byte("setSigConfig"), []byte(sigConfigEnvelope)); status != model.StatusCode(model.SignatureErrorType) {
+		t.Errorf("legacy owner setSigConfig = %d", status)
+	}
+
+	status, got := invoke(stub, []byte("migrateLegacyAddress"), []byte(legacyEnvelope))
+	if status != shim.OK || got != newAddr {
+		t.Fatalf("migrateLegacyAddress = %d %q", status, got)
+	}
+	expectQuery(t, stub, "1000", "balanceOf", newAddr)
+	expectQuery(t, stub, "1000", "balanceOf", legacyAddr)
+	expectQuery(t, stub, "50", "allowance", newAddr, testCarol)
+	expectQuery(t, stub, "50", "allowance", legacyAddr, testCarol)
+
+	// 매핑 후 기존 형식 주소로 보내면 새 주소로 입금된다.
+	bob := newTestWallet(t)
+	bobAddr := bob.address(t, stub)
+	stub.MockTransactionStart("seed")
+	if err := putBalance(stub, bobAddr, 10); err != nil {
+		t.Fatal(err)
+	}
+	stub.MockTransactionEnd("seed")
+	if status, msg := invoke(stub, []byte("transfer"), bob.envelope(t, legacyAddr+",10")); status != shim.OK {
+		t.Fatal("transfer to legacy address failed", msg)
+	}
+	expectQuery(t, stub, "1010", "balanceOf", newAddr)
+
+	if status, msg := invoke(stub, []byte("migrateLegacyAddress"), []byte(legacyEnvelope)); status != shim.OK {
+		t.Error("repeated migration failed", msg)
+	}
+	expectQuery(t, stub, "1010", "balanceOf", newAddr)
+
+	if status, _ := invoke(stub, []byte("migrateLegacyAddress"), bob.sign(t, wallet.WalletMeta{}, wallet.SigContext{})); status != shim.OK {
+		t.Error("migration without legacy balance failed")
+	}
+}
+
+func TestRecipientAddressValidation(t *testing.T) {
+	stub := shim.NewMockStub("chaincode", new(Chaincode))
+	alice := newTestWallet(t)
+	aliceAddr := alice.address(t, stub)
+	seedBalance(t, stub, aliceAddr, 100)
+
+	for _, to := range []string{"bob", "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBh", "BV8oLGiPd8qTGsEqqJ7b9V7X29VuQcea2C"} {
+		if status, msg := invoke(stub, []byte("transfer"), alice.envelope(t, to+",10")); status != model.StatusCode(model.AddressErrorType) {
+			t.Errorf("transfer to %s = %d %s", to, status, msg)
+		}
+		if status, _ := invoke(stub, []byte("approve"), alice.envelope(t, to+",10")); status != model.StatusCode(model.AddressErrorType) {
+			t.Errorf("approve %s = %d", to, status)
+		}
+		if status, _ := invoke(stub, []byte("validateAddress"), []byte(to)); status != model.StatusCode(model.AddressErrorType) {
+			t.Errorf("validateAddress %s = %d", to, status)
+		}
+	}
+	expectQuery(t, stub, "100", "balanceOf", aliceAddr)
+
+	expectQuery(t, stub, `{"address":"`+aliceAddr+`","version":128,"type":"p256"}`, "validateAddress", aliceAddr)
+	expectQuery(t, stub, `{"address":"`+testCarol+`","version":129,"type":"secp256k1"}`, "validateAddress", testCarol)
+}
+
+func TestCompressedPublicKeyWallet(t *testing.T) {
+	stub := shim.NewMockStub("chaincode", new(Chaincode))
+	alice := newTestWallet(t)
+	aliceAddr := alice.address(t, stub)
+	seedBalance(t, stub, aliceAddr, 100)
+
+	compressed := *alice
+	compressed.pub = hex.EncodeToString(elliptic.MarshalCompressed(alice.key.Curve, alice.key.X, alice.key.Y))
+	if addr := compressed.address(t, stub); addr != aliceAddr {
+		t.Fatalf("compressed key address %s, want %s", addr, aliceAddr)
+	}
+
+	if status, msg := invoke(stub, []byte("transfer"), compressed.envelope(t, testBob+",30")); status != shim.OK {
+		t.Fatal("transfer with compressed key failed", msg)
+	}
+	expectQuery(t, stub, "70", "balanceOf", aliceAddr)
+}
+
+// multisigEnvelope 은 signers 가 같은 canonical 메시지에 서명한 multisig 봉투를 만든다.
+func multisigEnvelope(t *testing.T, msAddr string, meta wallet.WalletMeta, ctx wallet.SigContext, signers ...*testWallet) []byte {
+	meta.Publickey = msAddr
+	meta.Keytype = wallet.KeyTypeMultisig
+	meta.Sigver = wallet.SigVerCanonical
+	meta.Txtime = strconv.FormatInt(time.Now().Unix(), 10)
+
+	msg := wallet.CanonicalMessage(meta, directCall(ctx))
+	for _, signer := range signers {
+		meta.Sigs = append(meta.Sigs, wallet.SignerSig{Publickey: signer.pub, Curve: signer.curve, Sigmsg: signer.signMessage(t, msg, meta.Sigver)})
+	}
+	metaBytes, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metaBytes
+}
+
+func TestMultisigWallet(t *testing.T) {
+	stub := shim.NewMockStub("erc20", new(Chaincode))
+	stub.ChannelID = "mychannel"
+	alice, bob, k1 := newTestWallet(t), newTestWallet(t), newCurveTestWallet(t, wallet.CurveSecp256k1, secp256k1.S256())
+	mallory := newTestWallet(t)
+	k1Addr, err := wallet.AddressFromPublicKey(k1.pub, k1.curve, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := []string{alice.address(t, stub), bob.address(t, stub), k1Addr}
+
+	policy := `{"threshold":2,"members":["` + strings.Join(members, `","`) + `"]}`
+	status, msAddr := invoke(stub, []byte("registerMultisig"), []byte(policy))
+	if status != shim.OK {
+		t.Fatal("registerMultisig failed", msAddr)
+	}
+	reversed := `{"threshold":2,"members":["` + members[2] + `","` + members[1] + `","` + members[0] + `"]}`
+	expectQuery(t, stub, msAddr, "registerMultisig", reversed)
+	expectQuery(t, stub, `{"address":"`+msAddr+`","version":131,"type":"multisig"}`, "validateAddress", msAddr)
+	seedBalance(t, stub, msAddr, 100)
+
+	ctx := wallet.SigContext{Function: "transfer", Channel: "mychannel", Chaincode: "erc20"}
+	cases := []struct {
+		name    string
+		nonce   string
+		signers []*testWallet
+		status  int32
+	}{
+		{"below threshold", "1", []*testWallet{alice}, model.StatusCode(model.SignatureErrorType)},
+		{"same member twice", "1", []*testWallet{alice, alice}, model.StatusCode(model.SignatureErrorType)},
+		{"non member", "1", []*testWallet{alice, mallory}, model.StatusCode(model.SignatureErrorType)},
+		{"two of three", "1", []*testWallet{alice, k1}, shim.OK},
+		{"replayed nonce", "1", []*testWallet{bob, k1}, model.StatusCode(model.NonceErrorType)},
+		{"all members", "2", []*testWallet{alice, bob, k1}, shim.OK},
+	}
+	for _, tc := range cases {
+		envelope := multisigEnvelope(t, msAddr, wallet.WalletMeta{Nonce: tc.nonce, Transdata: testBob + ",10"}, ctx, tc.signers...)
+		if status, msg := invokeSigned(t, stub, []byte("transfer"), envelope); status != tc.status {
+			t.Errorf("%s: status %d %s, want %d", tc.name, status, msg, tc.status)
+		}
+	}
+	expectQuery(t, stub, "80", "balanceOf", msAddr)
+	expectQuery(t, stub, "20", "balanceOf", testBob)
+
+	unregistered := multisigEnvelope(t, testCarol, wallet.WalletMeta{Nonce: "1", Transdata: testBob + ",10"}, ctx, alice, bob)
+	if status, _ := invokeSigned(t, stub, []byte("transfer"), unregistered); status != model.StatusCode(model.PublicKeyErrorType) {
+		t.Errorf("unregistered multisig = %d", status)
+	}
+}
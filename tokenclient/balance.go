@@ -0,0 +1,99 @@
+package tokenclient
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jinsan74/Erc20/model"
+)
+
+// Balance is 소수 자릿수를 포함한 잔액
+type Balance struct {
+	Address  string
+	Amount   *big.Int // 최소 단위 금액
+	Decimals uint8
+}
+
+// Uint64 는 최소 단위 금액을 uint64 로 반환한다.
+func (b Balance) Uint64() (uint64, error) {
+	if !b.Amount.IsUint64() {
+		return 0, model.NewCustomError(model.OverflowErrorType, b.Address, "balance "+b.Amount.String()+" exceeds uint64")
+	}
+	return b.Amount.Uint64(), nil
+}
+
+// String 은 소수 자릿수를 적용한 금액 (예: Amount 1050, Decimals 2 이면 "10.50")
+func (b Balance) String() string {
+	digits := b.Amount.String()
+	decimals := int(b.Decimals)
+	if decimals == 0 {
+		return digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	return digits[:len(digits)-decimals] + "." + digits[len(digits)-decimals:]
+}
+
+// Balance 는 address 의 잔액과 토큰 소수 자릿수를 조회한다.
+func (c *Client) Balance(address string) (*Balance, error) {
+	balances, err := c.BalanceOfBatch(address)
+	if err != nil {
+		return nil, err
+	}
+	return &balances[0], nil
+}
+
+// BalanceOfBatch 는 addresses 의 잔액을 한 번에 조회해서 요청한 순서대로 반환한다.
+func (c *Client) BalanceOfBatch(addresses ...string) ([]Balance, error) {
+	payload, err := c.invoke("balanceOfBatch", addresses...)
+	if err != nil {
+		return nil, err
+	}
+	typeName := c.ChaincodeName + " balanceOfBatch"
+
+	batch := model.BalanceBatch{}
+	decoder := json.NewDecoder(strings.NewReader(string(payload)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&batch); err != nil {
+		return nil, model.NewCustomError(model.UnMarshalErrorType, typeName, err.Error())
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, model.NewCustomError(model.UnMarshalErrorType, typeName, "unexpected data after balances")
+	}
+	if len(batch.Balances) != len(addresses) {
+		return nil, model.NewCustomError(model.UnMarshalErrorType, typeName, "expecting "+strconv.Itoa(len(addresses))+" balances, got "+strconv.Itoa(len(batch.Balances)))
+	}
+
+	balances := make([]Balance, len(addresses))
+	for i, entry := range batch.Balances {
+		if entry.Address != addresses[i] {
+			return nil, model.NewCustomError(model.UnMarshalErrorType, typeName, "balance "+strconv.Itoa(i)+" is for "+entry.Address+", expecting "+addresses[i])
+		}
+		amount, err := parseAmount(typeName, entry.Balance)
+		if err != nil {
+			return nil, err
+		}
+		balances[i] = Balance{Address: entry.Address, Amount: amount, Decimals: batch.Decimals}
+	}
+	return balances, nil
+}
+
+// parseAmount 는 최소 단위 금액 문자열을 확인한다. 부호나 앞자리 0 이 없는 10진수만 받는다.
+func parseAmount(typeName, value string) (*big.Int, error) {
+	valid := value != "" && (value == "0" || value[0] != '0')
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			valid = false
+			break
+		}
+	}
+	if !valid {
+		return nil, model.NewCustomError(model.ConvertErrorType, typeName, "balance is not an unsigned integer: "+strconv.Quote(value))
+	}
+	amount, _ := new(big.Int).SetString(value, 10)
+	return amount, nil
+}
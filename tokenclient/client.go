@@ -0,0 +1,225 @@
+// Package tokenclient 는 다른 체인코드에서 토큰 체인코드를 호출하는 클라이언트
+//
+// 지갑형 함수는 사용자가 서명한 지갑 파라미터(WalletMeta)를 바꾸지 않고 전달한다.
+// 서명한 transdata (TransferMulti 계열은 transjdata) 가 호출하려는 값과 다르면 토큰 체인코드를 호출하지 않고 오류를 반환한다.
+// canonical 서명(sigver 2, 3)은 실행되는 토큰 체인코드와 함수, transdata 까지 서명하므로 호출한 체인코드가 다른 작업으로 바꿀 수 없다.
+package tokenclient
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+// Client is 토큰 체인코드 호출 클라이언트
+type Client struct {
+	Stub          shim.ChaincodeStubInterface
+	ChaincodeName string // 호출할 토큰 체인코드 이름
+	Channel       string // 토큰 체인코드 채널, 비어 있으면 Stub 의 채널
+	Envelope      string // 전달할 지갑 파라미터, 비어 있으면 호출한 트랜잭션의 지갑 파라미터
+}
+
+// New 는 stub 의 채널에 있는 chaincodeName 토큰 체인코드 클라이언트를 만든다.
+func New(stub shim.ChaincodeStubInterface, chaincodeName string) *Client {
+	return &Client{Stub: stub, ChaincodeName: chaincodeName}
+}
+
+// Transfer 는 서명자 지갑에서 to 로 amount 를 보낸다.
+func (c *Client) Transfer(to string, amount uint64) error {
+	if err := wallet.ValidateAddress(to); err != nil {
+		return err
+	}
+	_, err := c.forward("transfer", to+","+strconv.FormatUint(amount, 10))
+	return err
+}
+
+// Approve 는 서명자 지갑의 spender 위임 한도를 amount 로 정한다.
+func (c *Client) Approve(spender string, amount uint64) error {
+	if err := wallet.ValidateAddress(spender); err != nil {
+		return err
+	}
+	_, err := c.forward("approve", spender+","+strconv.FormatUint(amount, 10))
+	return err
+}
+
+// TransferFrom 은 서명자(spender)의 위임 한도로 from 에서 to 로 amount 를 보낸다.
+func (c *Client) TransferFrom(from, to string, amount uint64) error {
+	if err := wallet.ValidateAddress(to); err != nil {
+		return err
+	}
+	_, err := c.forward("transferFrom", from+","+to+","+strconv.FormatUint(amount, 10))
+	return err
+}
+
+// Mint 는 to 에게 amount 를 새로 발행한다.
+func (c *Client) Mint(to string, amount uint64) error {
+	if err := wallet.ValidateAddress(to); err != nil {
+		return err
+	}
+	_, err := c.forward("mint", to+","+strconv.FormatUint(amount, 10))
+	return err
+}
+
+// Burn 은 서명자 지갑에서 amount 를 소각한다.
+func (c *Client) Burn(amount uint64) error {
+	_, err := c.forward("burn", strconv.FormatUint(amount, 10))
+	return err
+}
+
+// TransferMulti 는 서명자 지갑에서 여러 주소로 보낸다. 하나라도 실패하면 모두 실패한다.
+// 이 저장소의 토큰 체인코드는 transferMulti 를 처리하지 않으므로 이를 지원하는 토큰 체인코드에 사용한다.
+func (c *Client) TransferMulti(transfers []wallet.TransferMeta) error {
+	return c.transferMulti("transferMulti", transfers)
+}
+
+// TransferMultiNonSafety 는 TransferMulti 와 같지만 토큰 체인코드가 잔액 확인을 생략한다.
+func (c *Client) TransferMultiNonSafety(transfers []wallet.TransferMeta) error {
+	return c.transferMulti("transferMultiNonSafety", transfers)
+}
+
+// TransferMultiNonSafetyN 은 보내는 주소를 항목마다 지정하는 TransferMultiNonSafety
+func (c *Client) TransferMultiNonSafetyN(transfers []wallet.TransferMetaN) error {
+	for _, transfer := range transfers {
+		if err := transfer.Validate(); err != nil {
+			return err
+		}
+	}
+	signed := []wallet.TransferMetaN{}
+	return c.forwardJSON("transferMultiNoneSafetyN", transfers, &signed)
+}
+
+// Call 은 transdata 로 서명한 지갑 파라미터로 function 을 호출하고 응답 payload 를 반환한다.
+// 전용 메소드가 없는 지갑형 함수에 사용한다.
+func (c *Client) Call(function string, transdata string) ([]byte, error) {
+	return c.forward(function, transdata)
+}
+
+// VerifySigner 는 호출한 트랜잭션의 지갑 파라미터를 바꾸지 않고 토큰 체인코드에 전달해서 서명자를 확인한다.
+// 토큰 체인코드의 계정 레지스트리, nonce, 서명 설정이 적용되므로 다른 체인코드는 직접 서명을 검증하지 않는다.
+func (c *Client) VerifySigner() (*wallet.Signer, error) {
+	envelope, err := c.envelope()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := c.invoke("verifySigner", envelope)
+	if err != nil {
+		return nil, err
+	}
+	signer := wallet.Signer{}
+	if err := json.Unmarshal(payload, &signer); err != nil || signer.Address == "" {
+		return nil, model.NewCustomError(model.UnMarshalErrorType, c.ChaincodeName+" verifySigner", "unexpected signer "+string(payload))
+	}
+	return &signer, nil
+}
+
+// HasRole 은 address 에게 토큰 체인코드의 role 이 있는지 확인한다.
+func (c *Client) HasRole(address, role string) (bool, error) {
+	payload, err := c.invoke("hasRole", address, role)
+	if err != nil {
+		return false, err
+	}
+	ok, err := strconv.ParseBool(string(payload))
+	if err != nil {
+		return false, model.NewCustomError(model.InvokeChaincodeErrorType, c.ChaincodeName+" hasRole", "unexpected hasRole result "+strconv.Quote(string(payload)))
+	}
+	return ok, nil
+}
+
+// BalanceOf 는 address 의 최소 단위 잔액을 조회한다. 소수 자릿수가 필요하면 Balance 를 사용한다.
+func (c *Client) BalanceOf(address string) (uint64, error) {
+	payload, err := c.invoke("balanceOf", address)
+	if err != nil {
+		return 0, err
+	}
+	amount, err := parseAmount(c.ChaincodeName+" balanceOf", string(payload))
+	if err != nil {
+		return 0, err
+	}
+	return Balance{Address: address, Amount: amount}.Uint64()
+}
+
+func (c *Client) transferMulti(function string, transfers []wallet.TransferMeta) error {
+	for _, transfer := range transfers {
+		if err := transfer.Validate(); err != nil {
+			return err
+		}
+	}
+	signed := []wallet.TransferMeta{}
+	return c.forwardJSON(function, transfers, &signed)
+}
+
+// forward 는 지갑 파라미터를 바꾸지 않고 function 에 전달한다. 서명한 transdata 가 transdata 와 같아야 한다.
+func (c *Client) forward(function string, transdata string) ([]byte, error) {
+	return c.forwardSigned(function, "transdata", strconv.Quote(transdata), func(walletMeta wallet.WalletMeta) bool {
+		return walletMeta.Transdata == transdata
+	})
+}
+
+// forwardJSON 은 서명한 transjdata 를 signed 에 디코딩한 값이 v 와 같을 때만 지갑 파라미터를 function 에 전달한다.
+func (c *Client) forwardJSON(function string, v interface{}, signed interface{}) error {
+	jdata, err := json.Marshal(v)
+	if err != nil {
+		return model.NewCustomError(model.MarshalErrorType, function, err.Error())
+	}
+	_, err = c.forwardSigned(function, "transjdata", string(jdata), func(walletMeta wallet.WalletMeta) bool {
+		decoder := json.NewDecoder(strings.NewReader(walletMeta.Transjdata))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(signed); err != nil {
+			return false
+		}
+		return reflect.DeepEqual(reflect.ValueOf(signed).Elem().Interface(), v)
+	})
+	return err
+}
+
+// forwardSigned 는 지갑 파라미터가 signed 를 만족할 때만 바꾸지 않고 function 에 전달한다.
+func (c *Client) forwardSigned(function, field, want string, signed func(walletMeta wallet.WalletMeta) bool) ([]byte, error) {
+	envelope, err := c.envelope()
+	if err != nil {
+		return nil, err
+	}
+	walletMeta, err := wallet.ParseWalletMeta(envelope)
+	if err != nil {
+		return nil, err
+	}
+	if !signed(walletMeta) {
+		return nil, model.NewCustomError(model.SignatureErrorType, field, "wallet parameter is not signed for "+function+" "+want)
+	}
+	return c.invoke(function, envelope)
+}
+
+// envelope 는 전달할 지갑 파라미터 (Envelope 또는 호출한 트랜잭션의 지갑 파라미터)
+func (c *Client) envelope() (string, error) {
+	if c.Envelope != "" {
+		return c.Envelope, nil
+	}
+	_, orgParam := c.Stub.GetFunctionAndParameters()
+	if len(orgParam) != 1 {
+		return "", model.NewCustomError(model.MandatoryPrameterErrorType, "Wallet Parameter", "expecting exactly one wallet parameter")
+	}
+	return orgParam[0], nil
+}
+
+// invoke 는 토큰 체인코드의 function 을 호출하고, 실패 응답이면 토큰 체인코드의 오류를 그대로 담은 CustomError 를 반환한다.
+func (c *Client) invoke(function string, args ...string) ([]byte, error) {
+	invokeArgs := make([][]byte, 0, len(args)+1)
+	invokeArgs = append(invokeArgs, []byte(function))
+	for _, arg := range args {
+		invokeArgs = append(invokeArgs, []byte(arg))
+	}
+
+	channel := c.Channel
+	if channel == "" {
+		channel = c.Stub.GetChannelID()
+	}
+	response := c.Stub.InvokeChaincode(c.ChaincodeName, invokeArgs, channel)
+	if response.Status != shim.OK {
+		return nil, model.InvokeError(c.ChaincodeName, function, response)
+	}
+	return response.Payload, nil
+}
@@ -0,0 +1,235 @@
+package tokenclient
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/wallet"
+)
+
+const testAddr = "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg"
+
+// fakeToken 은 받은 호출을 기록하고 balanceOf 에 balance 를 응답하는 토큰 체인코드
+type fakeToken struct {
+	function string
+	envelope string // 받은 지갑 파라미터
+	meta     wallet.WalletMeta
+	balance  string
+	batch    string       // balanceOfBatch 응답
+	failure  *sc.Response // nil 이 아니면 모든 호출에 이 응답을 돌려준다
+}
+
+func (f *fakeToken) Init(stub shim.ChaincodeStubInterface) sc.Response { return shim.Success(nil) }
+
+func (f *fakeToken) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
+	function, params := stub.GetFunctionAndParameters()
+	f.function = function
+	if f.failure != nil {
+		return *f.failure
+	}
+	switch function {
+	case "balanceOf":
+		return shim.Success([]byte(f.balance))
+	case "balanceOfBatch":
+		return shim.Success([]byte(f.batch))
+	}
+	f.envelope, f.meta = params[0], wallet.WalletMeta{}
+	if err := json.Unmarshal([]byte(params[0]), &f.meta); err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// callerChaincode 는 호출한 지갑 파라미터로 call 을 실행한다.
+type callerChaincode struct {
+	call func(c *Client) error
+}
+
+func (cc *callerChaincode) Init(stub shim.ChaincodeStubInterface) sc.Response {
+	return shim.Success(nil)
+}
+
+func (cc *callerChaincode) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
+	if err := cc.call(New(stub, "token")); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+func newCaller(token *fakeToken, call func(c *Client) error) *shim.MockStub {
+	caller := &callerChaincode{call: call}
+	stub := shim.NewMockStub("caller", caller)
+	stub.ChannelID = "mychannel"
+	tokenStub := shim.NewMockStub("token", token)
+	stub.MockPeerChaincode("token/mychannel", tokenStub)
+	return stub
+}
+
+func TestClientForwardsEnvelope(t *testing.T) {
+	token := &fakeToken{}
+	cases := []struct {
+		call       func(c *Client) error
+		function   string
+		transdata  string
+		transjdata string
+	}{
+		{func(c *Client) error { return c.Transfer(testAddr, 10) }, "transfer", testAddr + ",10", "{}"},
+		{func(c *Client) error { return c.Approve(testAddr, 5) }, "approve", testAddr + ",5", "{}"},
+		{func(c *Client) error { return c.TransferFrom("legacy", testAddr, 7) }, "transferFrom", "legacy," + testAddr + ",7", "{}"},
+		{func(c *Client) error { return c.Mint(testAddr, 3) }, "mint", testAddr + ",3", "{}"},
+		{func(c *Client) error { return c.Burn(2) }, "burn", "2", "{}"},
+		{func(c *Client) error { _, err := c.Call("approve", testAddr+",0"); return err }, "approve", testAddr + ",0", "{}"},
+		{func(c *Client) error {
+			return c.TransferMulti([]wallet.TransferMeta{{Address: testAddr, Amount: 1}})
+		}, "transferMulti", "", `[{\"address\":\"` + testAddr + `\",\"amount\":1}]`},
+		{func(c *Client) error {
+			return c.TransferMultiNonSafety([]wallet.TransferMeta{{Address: testAddr, Amount: 2}})
+		}, "transferMultiNonSafety", "", `[{\"address\":\"` + testAddr + `\",\"amount\":2}]`},
+		{func(c *Client) error {
+			return c.TransferMultiNonSafetyN([]wallet.TransferMetaN{{FromAddress: "from", ToAddress: testAddr, Amount: 1}})
+		}, "transferMultiNoneSafetyN", "", `[{\"fromaddress\":\"from\",\"toaddress\":\"` + testAddr + `\",\"amount\":1}]`},
+	}
+	for _, c := range cases {
+		envelope := `{"publickey":"pub","txtime":"1","sigmsg":"sig","transdata":"` + c.transdata + `","transjdata":"` + c.transjdata + `"}`
+		stub := newCaller(token, c.call)
+		if res := stub.MockInvoke("tx", [][]byte{[]byte("fund"), []byte(envelope)}); res.Status != shim.OK {
+			t.Fatalf("%s failed: %s", c.function, res.Message)
+		}
+		if token.function != c.function || token.envelope != envelope {
+			t.Errorf("%s forwarded %s %s", c.function, token.function, token.envelope)
+		}
+
+		// 서명한 transdata (transjdata) 와 다른 호출은 토큰 체인코드로 전달하지 않는다.
+		token.function = ""
+		other := `{"publickey":"pub","txtime":"1","sigmsg":"sig","transdata":"` + testAddr + `,999"}`
+		if res := stub.MockInvoke("tx", [][]byte{[]byte("fund"), []byte(other)}); res.Status != model.StatusCode(model.SignatureErrorType) || token.function != "" {
+			t.Errorf("%s with other transdata = %d, called %q", c.function, res.Status, token.function)
+		}
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	token := &fakeToken{}
+	var balance uint64
+	stub := newCaller(token, func(c *Client) (err error) {
+		balance, err = c.BalanceOf(testAddr)
+		return err
+	})
+
+	token.balance = "42"
+	if res := stub.MockInvoke("tx", [][]byte{[]byte("fund")}); res.Status != shim.OK || balance != 42 {
+		t.Errorf("balanceOf = %d, %s", balance, res.Message)
+	}
+	token.balance = "-1"
+	if res := stub.MockInvoke("tx", [][]byte{[]byte("fund")}); res.Status != model.StatusCode(model.ConvertErrorType) {
+		t.Errorf("malformed balance = %d", res.Status)
+	}
+
+	// 토큰 체인코드의 오류 종류, 상태 코드와 내용을 그대로 돌려준다.
+	failure := model.ErrorResponse(model.NewCustomError(model.InsufficientBalanceErrorType, testAddr, "amount exceeds balance"))
+	token.failure = &failure
+	res := stub.MockInvoke("tx", [][]byte{[]byte("fund")})
+	customErr := model.ResponseError(res)
+	if res.Status != 460 || customErr == nil || customErr.ErrorType != model.InsufficientBalanceErrorType || customErr.TypeName != testAddr ||
+		customErr.Message != "amount exceeds balance" || customErr.Chaincode != "token" || customErr.Function != "balanceOf" {
+		t.Errorf("failed call = %d %+v", res.Status, customErr)
+	}
+
+	// CustomError 가 아닌 응답은 메시지와 payload 를 담는다.
+	failure = sc.Response{Status: shim.ERROR, Message: "chaincode panic", Payload: []byte("raw")}
+	res = stub.MockInvoke("tx", [][]byte{[]byte("fund")})
+	customErr = model.ResponseError(res)
+	if res.Status != shim.ERROR || customErr == nil || customErr.ErrorType != model.InvokeChaincodeErrorType || customErr.Message != "chaincode panic" ||
+		string(customErr.Payload) != "raw" || customErr.Chaincode != "token" {
+		t.Errorf("failed raw call = %d %+v", res.Status, customErr)
+	}
+
+	// 200 이 아닌 성공 범위의 상태 코드는 호출 실패로 바꿔서 성공 응답이 되지 않는다.
+	for _, status := range []int32{201, 302, 399} {
+		failure = sc.Response{Status: status, Message: "not ok"}
+		res = stub.MockInvoke("tx", [][]byte{[]byte("fund")})
+		customErr = model.ResponseError(res)
+		if res.Status != model.StatusCode(model.InvokeChaincodeErrorType) || customErr == nil || customErr.ErrorType != model.InvokeChaincodeErrorType || customErr.Chaincode != "token" {
+			t.Errorf("status %d call = %d %+v", status, res.Status, customErr)
+		}
+	}
+	payload, _ := json.Marshal(model.NewCustomError(model.InsufficientBalanceErrorType, testAddr, "amount exceeds balance"))
+	failure = sc.Response{Status: 250, Payload: payload}
+	if res = stub.MockInvoke("tx", [][]byte{[]byte("fund")}); res.Status != model.StatusCode(model.InvokeChaincodeErrorType) {
+		t.Errorf("custom error with status 250 = %d", res.Status)
+	}
+
+	// 잘못된 받는 주소나 지갑 파라미터는 호출 전에 거부한다.
+	token.failure, token.function = nil, ""
+	transfer := newCaller(token, func(c *Client) error { return c.Transfer("bob", 1) })
+	if res := transfer.MockInvoke("tx", [][]byte{[]byte("fund"), []byte("{}")}); res.Status != model.StatusCode(model.AddressErrorType) || token.function != "" {
+		t.Errorf("invalid recipient = %d, called %q", res.Status, token.function)
+	}
+	multi := newCaller(token, func(c *Client) error {
+		return c.TransferMulti([]wallet.TransferMeta{{Address: testAddr, Amount: 1}, {Address: "bob", Amount: 1}})
+	})
+	if res := multi.MockInvoke("tx", [][]byte{[]byte("fund"), []byte("{}")}); res.Status != model.StatusCode(model.AddressErrorType) || token.function != "" {
+		t.Errorf("invalid multi recipient = %d, called %q", res.Status, token.function)
+	}
+	burn := newCaller(token, func(c *Client) error { return c.Burn(1) })
+	if res := burn.MockInvoke("tx", [][]byte{[]byte("fund"), []byte(`{"unknown":1}`)}); res.Status != model.StatusCode(model.UnMarshalErrorType) || token.function != "" {
+		t.Errorf("invalid envelope = %d, called %q", res.Status, token.function)
+	}
+}
+
+func TestClientBalance(t *testing.T) {
+	token := &fakeToken{}
+	var balances []Balance
+	stub := newCaller(token, func(c *Client) (err error) {
+		balances, err = c.BalanceOfBatch(testAddr, "other")
+		return err
+	})
+
+	token.batch = `{"decimals":2,"balances":[{"address":"` + testAddr + `","balance":"1050"},{"address":"other","balance":"18446744073709551616"}]}`
+	if res := stub.MockInvoke("tx", [][]byte{[]byte("fund")}); res.Status != shim.OK {
+		t.Fatal("balanceOfBatch failed", res.Message)
+	}
+	if amount, err := balances[0].Uint64(); err != nil || amount != 1050 || balances[0].String() != "10.50" {
+		t.Errorf("balance %v %s, err %v", amount, balances[0], err)
+	}
+	if _, err := balances[1].Uint64(); err == nil || balances[1].String() != "184467440737095516.16" {
+		t.Errorf("big balance %s, err %v", balances[1], err)
+	}
+
+	for _, batch := range []string{
+		`{"decimals":2,"balances":[{"address":"` + testAddr + `","balance":"1"}]}`,
+		`{"decimals":2,"balances":[{"address":"other","balance":"1"},{"address":"` + testAddr + `","balance":"1"}]}`,
+		`{"decimals":2,"balances":[{"address":"` + testAddr + `","balance":"+1"},{"address":"other","balance":"1"}]}`,
+		`{"decimals":2,"balances":[{"address":"` + testAddr + `","balance":"01"},{"address":"other","balance":"1"}]}`,
+		`{"decimals":2,"balances":[{"address":"` + testAddr + `","balance":1},{"address":"other","balance":"1"}]}`,
+		`{"decimals":2,"total":"2","balances":[{"address":"` + testAddr + `","balance":"1"},{"address":"other","balance":"1"}]}`,
+		`{"decimals":2,"balances":[{"address":"` + testAddr + `","balance":"1"},{"address":"other","balance":"1"}]}{}`,
+	} {
+		token.batch = batch
+		if res := stub.MockInvoke("tx", [][]byte{[]byte("fund")}); res.Status == shim.OK {
+			t.Errorf("malformed batch accepted: %s", batch)
+		}
+	}
+}
+
+func TestBalanceString(t *testing.T) {
+	for _, c := range []struct {
+		amount   int64
+		decimals uint8
+		want     string
+	}{
+		{0, 0, "0"},
+		{5, 0, "5"},
+		{0, 2, "0.00"},
+		{5, 3, "0.005"},
+		{123456, 3, "123.456"},
+	} {
+		if got := (Balance{Amount: big.NewInt(c.amount), Decimals: c.decimals}).String(); got != c.want {
+			t.Errorf("%d with %d decimals = %s, want %s", c.amount, c.decimals, got, c.want)
+		}
+	}
+}
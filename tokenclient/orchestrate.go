@@ -0,0 +1,124 @@
+package tokenclient
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+)
+
+// Step is RunSteps 로 실행할 토큰 체인코드 호출 하나
+// Envelope 는 사용자가 이 Step 의 토큰 체인코드, 함수와 transdata 로 서명한 지갑 파라미터이다.
+// canonical 서명은 실행되는 체인코드와 함수까지 포함하므로 Step 마다 따로 서명해야 한다.
+type Step struct {
+	Token    string // 토큰 체인코드 이름
+	Function string
+	Envelope string
+	call     func(c *Client) ([]byte, error)
+}
+
+// StepResult is 성공한 Step 의 결과
+type StepResult struct {
+	Token    string
+	Function string
+	Payload  []byte
+}
+
+// TransferStep 은 Token 에서 서명자 지갑의 amount 를 to 로 보내는 Step
+func TransferStep(token, envelope, to string, amount uint64) Step {
+	return Step{Token: token, Function: "transfer", Envelope: envelope, call: func(c *Client) ([]byte, error) {
+		return nil, c.Transfer(to, amount)
+	}}
+}
+
+// TransferFromStep 은 Token 에서 서명자의 위임 한도로 from 의 amount 를 to 로 보내는 Step
+func TransferFromStep(token, envelope, from, to string, amount uint64) Step {
+	return Step{Token: token, Function: "transferFrom", Envelope: envelope, call: func(c *Client) ([]byte, error) {
+		return nil, c.TransferFrom(from, to, amount)
+	}}
+}
+
+// ApproveStep 은 Token 에서 서명자 지갑의 spender 위임 한도를 정하는 Step
+func ApproveStep(token, envelope, spender string, amount uint64) Step {
+	return Step{Token: token, Function: "approve", Envelope: envelope, call: func(c *Client) ([]byte, error) {
+		return nil, c.Approve(spender, amount)
+	}}
+}
+
+// MintStep 은 Token 에서 to 에게 amount 를 발행하는 Step
+func MintStep(token, envelope, to string, amount uint64) Step {
+	return Step{Token: token, Function: "mint", Envelope: envelope, call: func(c *Client) ([]byte, error) {
+		return nil, c.Mint(to, amount)
+	}}
+}
+
+// BurnStep 은 Token 에서 서명자 지갑의 amount 를 소각하는 Step
+func BurnStep(token, envelope string, amount uint64) Step {
+	return Step{Token: token, Function: "burn", Envelope: envelope, call: func(c *Client) ([]byte, error) {
+		return nil, c.Burn(amount)
+	}}
+}
+
+// CallStep 은 Token 의 지갑형 함수 function 을 transdata 로 호출하는 Step
+func CallStep(token, envelope, function, transdata string) Step {
+	return Step{Token: token, Function: function, Envelope: envelope, call: func(c *Client) ([]byte, error) {
+		return c.Call(function, transdata)
+	}}
+}
+
+// RunSteps 는 steps 를 순서대로 실행하고 각 Step 의 결과를 반환한다. 각 Step 은 자기 Envelope 를 그대로 토큰 체인코드에 전달한다.
+// 실패하면 나머지 Step 을 실행하지 않고, 실패한 Step 의 오류에 몇 번째 Step 인지와 완료된 Step 을 더한 오류를 반환한다.
+// 호출한 체인코드가 이 오류로 실패 응답(model.ErrorResponse)을 돌려주면 트랜잭션 전체가 보증되지 않으므로
+// 이미 실행한 Step 의 변경도 원장에 반영되지 않는다.
+//
+// Fabric 은 같은 트랜잭션에서 쓴 값을 다시 읽을 수 없어서 같은 토큰을 두 번 호출하면 두 번째 호출이 첫 번째 변경을 덮어쓴다.
+// 그래서 한 번의 RunSteps 에서 토큰마다 Step 은 하나만 받는다.
+func RunSteps(stub shim.ChaincodeStubInterface, steps ...Step) ([]StepResult, error) {
+	if len(steps) == 0 {
+		return nil, model.NewCustomError(model.MandatoryPrameterErrorType, "steps", "expecting at least one step")
+	}
+	tokens := map[string]bool{}
+	for i, step := range steps {
+		if step.Token == "" || step.Envelope == "" || step.call == nil {
+			return nil, model.NewCustomError(model.MandatoryPrameterErrorType, "steps", "step "+strconv.Itoa(i+1)+" has no token, envelope or call")
+		}
+		if tokens[step.Token] {
+			return nil, model.NewCustomError(model.MandatoryPrameterErrorType, step.Token, "token is called by more than one step")
+		}
+		tokens[step.Token] = true
+	}
+
+	results := make([]StepResult, 0, len(steps))
+	for i, step := range steps {
+		client := New(stub, step.Token)
+		client.Envelope = step.Envelope
+		payload, err := step.call(client)
+		if err != nil {
+			return results, stepError(i, steps, err)
+		}
+		results = append(results, StepResult{Token: step.Token, Function: step.Function, Payload: payload})
+	}
+	return results, nil
+}
+
+// stepError 는 steps[i] 의 오류에 실패한 Step 과 완료된 Step 을 더한다. 오류 종류와 상태 코드는 그대로 둔다.
+func stepError(i int, steps []Step, err error) *model.CustomError {
+	stepErr := *model.ToCustomError(err)
+	if stepErr.Chaincode == "" {
+		stepErr.Chaincode, stepErr.Function = steps[i].Token, steps[i].Function
+	}
+
+	completed := ""
+	for _, step := range steps[:i] {
+		if completed != "" {
+			completed += ", "
+		}
+		completed += step.Token + " " + step.Function
+	}
+	if completed == "" {
+		completed = "none"
+	}
+	stepErr.Message = fmt.Sprintf("step %d of %d (%s %s) failed: %s; completed steps: %s", i+1, len(steps), steps[i].Token, steps[i].Function, stepErr.Message, completed)
+	return &stepErr
+}
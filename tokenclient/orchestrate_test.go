@@ -0,0 +1,120 @@
+package tokenclient
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+)
+
+// newOrchestrator 는 tokens 를 채널의 토큰 체인코드로 등록하고, 호출하면 steps 를 실행하는 체인코드 stub 을 만든다.
+func newOrchestrator(tokens map[string]*fakeToken, results *[]StepResult, steps ...Step) *shim.MockStub {
+	caller := &callerChaincode{call: func(c *Client) (err error) {
+		*results, err = RunSteps(c.Stub, steps...)
+		return err
+	}}
+	stub := shim.NewMockStub("fund", caller)
+	stub.ChannelID = "mychannel"
+	for name, token := range tokens {
+		stub.MockPeerChaincode(name+"/mychannel", shim.NewMockStub(name, token))
+	}
+	return stub
+}
+
+// stepEnvelope 는 token 으로 transdata 를 서명한 것처럼 만든 지갑 파라미터
+func stepEnvelope(token, transdata string) string {
+	return `{"publickey":"pub","txtime":"1","sigmsg":"sig-` + token + `","transdata":"` + transdata + `"}`
+}
+
+func TestRunSteps(t *testing.T) {
+	failure := model.ErrorResponse(model.NewCustomError(model.InsufficientBalanceErrorType, testAddr, "amount exceeds balance"))
+
+	t.Run("success", func(t *testing.T) {
+		tokens := map[string]*fakeToken{"gold": {}, "silver": {}}
+		var results []StepResult
+		stub := newOrchestrator(tokens, &results, TransferStep("gold", stepEnvelope("gold", testAddr+",10"), testAddr, 10), MintStep("silver", stepEnvelope("silver", testAddr+",5"), testAddr, 5))
+		if res := stub.MockInvoke("tx", [][]byte{[]byte("settle")}); res.Status != shim.OK {
+			t.Fatal("RunSteps failed", res.Message)
+		}
+		if len(results) != 2 || results[0].Token != "gold" || results[1].Function != "mint" {
+			t.Errorf("results %+v", results)
+		}
+		if tokens["gold"].meta.Transdata != testAddr+",10" || tokens["silver"].meta.Transdata != testAddr+",5" {
+			t.Errorf("forwarded %q %q", tokens["gold"].meta.Transdata, tokens["silver"].meta.Transdata)
+		}
+		// 각 토큰은 그 Step 으로 서명한 지갑 파라미터를 받는다.
+		if tokens["gold"].meta.Sigmsg != "sig-gold" || tokens["silver"].meta.Sigmsg != "sig-silver" {
+			t.Errorf("forwarded envelopes %q %q", tokens["gold"].meta.Sigmsg, tokens["silver"].meta.Sigmsg)
+		}
+	})
+
+	// 중간 Step 이 실패하면 다음 Step 을 호출하지 않고 체인코드 응답도 실패한다.
+	// MockStub 은 실패한 트랜잭션의 변경을 되돌리지 않지만 Fabric 은 실패 응답의 트랜잭션을 보증하지 않는다.
+	t.Run("downstream failure", func(t *testing.T) {
+		tokens := map[string]*fakeToken{"gold": {}, "silver": {failure: &failure}, "bronze": {}}
+		var results []StepResult
+		stub := newOrchestrator(tokens, &results, TransferStep("gold", stepEnvelope("gold", testAddr+",10"), testAddr, 10), TransferStep("silver", stepEnvelope("silver", testAddr+",20"), testAddr, 20), BurnStep("bronze", stepEnvelope("bronze", "1"), 1))
+		res := stub.MockInvoke("tx", [][]byte{[]byte("settle")})
+		customErr := model.ResponseError(res)
+		if res.Status != model.StatusCode(model.InsufficientBalanceErrorType) || customErr == nil || customErr.Chaincode != "silver" {
+			t.Fatalf("failed step = %d %+v", res.Status, customErr)
+		}
+		if !strings.Contains(customErr.Message, "step 2 of 3 (silver transfer)") || !strings.Contains(customErr.Message, "completed steps: gold transfer") {
+			t.Errorf("aggregated message %q", customErr.Message)
+		}
+		if len(results) != 1 || results[0].Token != "gold" {
+			t.Errorf("results %+v", results)
+		}
+		if tokens["gold"].function != "transfer" || tokens["bronze"].function != "" {
+			t.Errorf("called gold %q, bronze %q", tokens["gold"].function, tokens["bronze"].function)
+		}
+	})
+
+	// 호출 전 검증에 실패한 Step 도 같은 방식으로 중단한다.
+	t.Run("validation failure", func(t *testing.T) {
+		tokens := map[string]*fakeToken{"gold": {}, "silver": {}}
+		var results []StepResult
+		stub := newOrchestrator(tokens, &results, TransferStep("gold", stepEnvelope("gold", testAddr+",10"), testAddr, 10), TransferStep("silver", stepEnvelope("silver", "bob,20"), "bob", 20))
+		res := stub.MockInvoke("tx", [][]byte{[]byte("settle")})
+		customErr := model.ResponseError(res)
+		if res.Status != model.StatusCode(model.AddressErrorType) || customErr == nil || customErr.Chaincode != "silver" || customErr.Function != "transfer" {
+			t.Errorf("invalid step = %d %+v", res.Status, customErr)
+		}
+		if tokens["silver"].function != "" {
+			t.Error("invalid step was called")
+		}
+	})
+
+	t.Run("first step failure", func(t *testing.T) {
+		raw := sc.Response{Status: shim.ERROR, Message: "token paused"}
+		tokens := map[string]*fakeToken{"gold": {failure: &raw}, "silver": {}}
+		var results []StepResult
+		stub := newOrchestrator(tokens, &results, BurnStep("gold", stepEnvelope("gold", "1"), 1), BurnStep("silver", stepEnvelope("silver", "1"), 1))
+		res := stub.MockInvoke("tx", [][]byte{[]byte("settle")})
+		customErr := model.ResponseError(res)
+		if res.Status != shim.ERROR || customErr == nil || !strings.Contains(customErr.Message, "token paused; completed steps: none") || len(results) != 0 {
+			t.Errorf("first step = %d %+v %+v", res.Status, customErr, results)
+		}
+	})
+
+	t.Run("plan rejected", func(t *testing.T) {
+		tokens := map[string]*fakeToken{"gold": {}}
+		for _, steps := range [][]Step{
+			nil,
+			{TransferStep("gold", stepEnvelope("gold", testAddr+",1"), testAddr, 1), BurnStep("gold", stepEnvelope("gold", "1"), 1)},
+			{{Token: "gold", Function: "transfer"}},
+			{TransferStep("gold", "", testAddr, 1)},
+		} {
+			var results []StepResult
+			stub := newOrchestrator(tokens, &results, steps...)
+			if res := stub.MockInvoke("tx", [][]byte{[]byte("settle")}); res.Status != model.StatusCode(model.MandatoryPrameterErrorType) {
+				t.Errorf("plan %d steps = %d", len(steps), res.Status)
+			}
+			if tokens["gold"].function != "" {
+				t.Error("rejected plan called token")
+			}
+		}
+	})
+}
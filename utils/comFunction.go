@@ -2,7 +2,6 @@ package utils
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"runtime"
 	"strconv"
@@ -12,130 +11,65 @@ import (
 	sc "github.com/hyperledger/fabric/protos/peer"
 
 	"github.com/jinsan74/Erc20/model"
+	"github.com/jinsan74/Erc20/tokenclient"
 	"github.com/jinsan74/Erc20/wallet"
 )
 
 var logger = shim.NewLogger("sto-logger")
 
 // DoTransfer is 토큰 Transfer
+//
+// Deprecated: tokenclient.Client 의 Transfer 를 사용한다.
 func DoTransfer(stub shim.ChaincodeStubInterface, transParam string, tokenName string) sc.Response {
 
-	_, orgParam := stub.GetFunctionAndParameters()
-
-	walletMeta := wallet.WalletMeta{}
-	json.Unmarshal([]byte(orgParam[0]), &walletMeta)
-	walletMeta.Transdata = transParam
-
-	realTrans, _ := json.Marshal(walletMeta)
-
-	chainCodeFunc := "transfer"
-	invokeArgs := ToChaincodeArgs(chainCodeFunc, string(realTrans))
-	channel := stub.GetChannelID()
-	response := stub.InvokeChaincode(tokenName, invokeArgs, channel)
-
-	return response
+	if err := wallet.ValidateAddress(strings.Split(transParam, ",")[0]); err != nil {
+		return model.ErrorResponse(err)
+	}
+	return clientResponse(tokenclient.New(stub, tokenName).Call("transfer", transParam))
 }
 
 // DoBalanceOf is 토큰 balanceOf
+//
+// Deprecated: tokenclient.Client 의 BalanceOf 를 사용한다.
 func DoBalanceOf(stub shim.ChaincodeStubInterface, toaddress string, tokenName string) sc.Response {
 
-	// 지갑형 트랜잭션 VAILD WALLET CHECK 및 지갑주소/파라미터 파싱
-	chainCodeFunc := "balanceOf"
-	invokeArgs := ToChaincodeArgs(chainCodeFunc, toaddress)
-	channel := stub.GetChannelID()
-	response := stub.InvokeChaincode(tokenName, invokeArgs, channel)
-
-	if response.Status != shim.OK {
-		errStr := fmt.Sprintf("Failed to balanceOf chaincode. Got error: %s", string(response.Payload))
-		fmt.Printf(errStr)
-		return sc.Response{Status: 501, Message: "balanceOf Fail!", Payload: nil}
+	balance, err := tokenclient.New(stub, tokenName).BalanceOf(toaddress)
+	if err != nil {
+		return model.ErrorResponse(err)
 	}
-
-	return response
+	return shim.Success([]byte(strconv.FormatUint(balance, 10)))
 }
 
 // DoTokenFunc is 토큰 함수 실행 (burn, mint)
+//
+// Deprecated: tokenclient.Client 의 Mint, Burn, Call 을 사용한다.
 func DoTokenFunc(stub shim.ChaincodeStubInterface, funcName string, transParam string, tokenName string) sc.Response {
-	_, orgParam := stub.GetFunctionAndParameters()
-	walletMeta := wallet.WalletMeta{}
-	json.Unmarshal([]byte(orgParam[0]), &walletMeta)
-	walletMeta.Transdata = transParam
-	realTrans, _ := json.Marshal(walletMeta)
-	chainCodeFunc := funcName
-	invokeArgs := ToChaincodeArgs(chainCodeFunc, string(realTrans))
-	channel := stub.GetChannelID()
-	response := stub.InvokeChaincode(tokenName, invokeArgs, channel)
-	return response
+	return clientResponse(tokenclient.New(stub, tokenName).Call(funcName, transParam))
 }
 
 // DoTransferMulti is 토큰 TransferMulti
+//
+// Deprecated: tokenclient.Client 의 TransferMulti 를 사용한다.
 func DoTransferMulti(stub shim.ChaincodeStubInterface, stTransferMetaArr []wallet.TransferMeta, tokenName string) sc.Response {
-
-	_, orgParam := stub.GetFunctionAndParameters()
-	walletMeta := wallet.WalletMeta{}
-	json.Unmarshal([]byte(orgParam[0]), &walletMeta)
-	stTransferStr, _ := json.Marshal(stTransferMetaArr)
-	walletMeta.Transjdata = string(stTransferStr)
-	realTrans, _ := json.Marshal(walletMeta)
-
-	chainCodeFunc := "transferMulti"
-	invokeArgs := ToChaincodeArgs(chainCodeFunc, string(realTrans))
-	channel := stub.GetChannelID()
-	response := stub.InvokeChaincode(tokenName, invokeArgs, channel)
-
-	if response.Status != shim.OK {
-		errStr := fmt.Sprintf("Failed to transfer chaincode. Got error: %s", string(response.Payload))
-		fmt.Printf(errStr)
-		return sc.Response{Status: 501, Message: "transfer Fail!", Payload: nil}
-	}
-
-	return response
+	return clientResponse(nil, tokenclient.New(stub, tokenName).TransferMulti(stTransferMetaArr))
 }
 
+// Deprecated: tokenclient.Client 의 TransferMultiNonSafety 를 사용한다.
 func DoTransferMultiNoneSafety(stub shim.ChaincodeStubInterface, stTransferMetaArr []wallet.TransferMeta, tokenName string) sc.Response {
-
-	_, orgParam := stub.GetFunctionAndParameters()
-	walletMeta := wallet.WalletMeta{}
-	json.Unmarshal([]byte(orgParam[0]), &walletMeta)
-	stTransferStr, _ := json.Marshal(stTransferMetaArr)
-	walletMeta.Transjdata = string(stTransferStr)
-	realTrans, _ := json.Marshal(walletMeta)
-
-	chainCodeFunc := "transferMultiNonSafety"
-	invokeArgs := ToChaincodeArgs(chainCodeFunc, string(realTrans))
-	channel := stub.GetChannelID()
-	response := stub.InvokeChaincode(tokenName, invokeArgs, channel)
-
-	if response.Status != shim.OK {
-		errStr := fmt.Sprintf("Failed to transfer chaincode. Got error: %s", string(response.Payload))
-		fmt.Printf(errStr)
-		return sc.Response{Status: 501, Message: "transfer Fail!", Payload: nil}
-	}
-
-	return response
+	return clientResponse(nil, tokenclient.New(stub, tokenName).TransferMultiNonSafety(stTransferMetaArr))
 }
 
+// Deprecated: tokenclient.Client 의 TransferMultiNonSafetyN 을 사용한다.
 func DoTransferMultiNoneSafetyN(stub shim.ChaincodeStubInterface, stTransferMetaArr []wallet.TransferMetaN, tokenName string) sc.Response {
+	return clientResponse(nil, tokenclient.New(stub, tokenName).TransferMultiNonSafetyN(stTransferMetaArr))
+}
 
-	_, orgParam := stub.GetFunctionAndParameters()
-	walletMeta := wallet.WalletMeta{}
-	json.Unmarshal([]byte(orgParam[0]), &walletMeta)
-	stTransferStr, _ := json.Marshal(stTransferMetaArr)
-	walletMeta.Transjdata = string(stTransferStr)
-	realTrans, _ := json.Marshal(walletMeta)
-
-	chainCodeFunc := "transferMultiNoneSafetyN"
-	invokeArgs := ToChaincodeArgs(chainCodeFunc, string(realTrans))
-	channel := stub.GetChannelID()
-	response := stub.InvokeChaincode(tokenName, invokeArgs, channel)
-
-	if response.Status != shim.OK {
-		errStr := fmt.Sprintf("Failed to transfer chaincode. Got error: %s", string(response.Payload))
-		//fmt.Printf(errStr)
-		return sc.Response{Status: 501, Message: errStr, Payload: nil}
+// clientResponse 는 tokenclient 호출 결과를 응답으로 만든다.
+func clientResponse(payload []byte, err error) sc.Response {
+	if err != nil {
+		return model.ErrorResponse(err)
 	}
-
-	return response
+	return shim.Success(payload)
 }
 
 // DoTransferMulti is 토큰 TransferMulti
@@ -249,43 +183,71 @@ func CreateCompositKeyAndPut(stub shim.ChaincodeStubInterface, keytype string, k
 func ConvertStringToUint64(typeName, value string) (*uint64, error) {
 
 	// check amount is integer & positive
-	intValue, err := strconv.Atoi(value)
+	if strings.HasPrefix(value, "-") {
+		return nil, model.NewCustomError(model.ConvertErrorType, typeName, " must be positive")
+	}
 
+	// uint64 범위 전체를 받는다. (Atoi 는 int 범위까지만 받는다)
+	uint64Value, err := strconv.ParseUint(value, 10, 64)
 	if err != nil {
 		return nil, model.NewCustomError(model.ConvertErrorType, typeName, " must be integer")
 	}
-
-	if intValue < 0 {
-		return nil, model.NewCustomError(model.ConvertErrorType, typeName, " must be positive")
-	}
-	uint64Value := uint64(intValue)
 	return &uint64Value, nil
 }
 
-func GetFundAdmin(stub shim.ChaincodeStubInterface, fundid string) string {
+// FundChaincodeName 은 펀드 레지스트리 체인코드 이름
+const FundChaincodeName = "fund"
+
+// GetFundAdmin is 펀드 대표 관리자 지갑주소 조회
+// 조회에 실패하거나 응답이 지갑주소가 아니면 오류를 반환한다.
+func GetFundAdmin(stub shim.ChaincodeStubInterface, fundid string) (string, error) {
 
 	chainCodeFunc := "fundAddressSearch"
 	invokeArgs := ToChaincodeArgs(chainCodeFunc, fundid)
 	channel := stub.GetChannelID()
-	response := stub.InvokeChaincode("fund", invokeArgs, channel)
+	response := stub.InvokeChaincode(FundChaincodeName, invokeArgs, channel)
+	if response.Status != shim.OK {
+		return "", model.InvokeError(FundChaincodeName, chainCodeFunc, response)
+	}
 
 	address := string(response.Payload)
+	if err := wallet.ValidateAddress(address); err != nil {
+		return "", model.NewCustomError(model.FundErrorType, fundid, "fund admin is not a wallet address: "+err.Error())
+	}
+	return address, nil
+}
 
-	fmt.Println("FUND ADMIN:", fundid, address)
+// HasFundRole is 펀드 역할 확인 (model.FundRoleOwner, FundRoleAdmin, FundRoleOperator)
+// 조회에 실패하면 false 와 오류를 반환한다.
+func HasFundRole(stub shim.ChaincodeStubInterface, fundid string, address string, role string) (bool, error) {
 
-	//fundMeta := FundMeta{}
-	//json.Unmarshal([]byte(response.Payload), &fundMeta)
+	chainCodeFunc := "hasFundRole"
+	invokeArgs := ToChaincodeArgs(chainCodeFunc, fundid, address, role)
+	channel := stub.GetChannelID()
+	response := stub.InvokeChaincode(FundChaincodeName, invokeArgs, channel)
+	if response.Status != shim.OK {
+		return false, model.InvokeError(FundChaincodeName, chainCodeFunc, response)
+	}
 
-	return address
+	switch string(response.Payload) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, model.NewCustomError(model.FundErrorType, fundid, "unexpected hasFundRole result "+strconv.Quote(string(response.Payload)))
 }
 
-func IsFundAdmin(stub shim.ChaincodeStubInterface, fundid string, owneraddress string) bool {
-	adminaddress := GetFundAdmin(stub, fundid)
-	if adminaddress == owneraddress {
-		return true
-	} else {
-		return false
-	}
+// IsFundAdmin is 펀드 대표 관리자(owner, fundAddressSearch 결과) 확인
+// admin 도 허용하려면 IsFundAdminOrAbove 를 사용한다. 조회에 실패하면 false 와 오류를 반환한다.
+func IsFundAdmin(stub shim.ChaincodeStubInterface, fundid string, owneraddress string) (bool, error) {
+	return HasFundRole(stub, fundid, owneraddress, model.FundRoleOwner)
+}
+
+// IsFundAdminOrAbove is 펀드 관리자(owner 또는 admin) 확인
+// 조회에 실패하면 false 와 오류를 반환한다.
+func IsFundAdminOrAbove(stub shim.ChaincodeStubInterface, fundid string, address string) (bool, error) {
+	return HasFundRole(stub, fundid, address, model.FundRoleAdmin)
 }
 
 // shim 로그를 사용하기 위해서는 반드시 초기화가 필요함
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/jinsan74/Erc20/model"
+)
+
+func TestConvertStringToUint64(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  uint64
+	}{
+		{"0", 0},
+		{"1000", 1000},
+		{"9223372036854775808", 9223372036854775808},
+		{"18446744073709551615", 18446744073709551615},
+	} {
+		got, err := ConvertStringToUint64("amount", tc.value)
+		if err != nil || *got != tc.want {
+			t.Errorf("ConvertStringToUint64(%q) = %v, %v", tc.value, got, err)
+		}
+	}
+
+	for _, value := range []string{"", "-1", "+1", "1.5", "abc", "18446744073709551616"} {
+		if _, err := ConvertStringToUint64("amount", value); model.ToCustomError(err) == nil || model.ToCustomError(err).ErrorType != model.ConvertErrorType {
+			t.Errorf("ConvertStringToUint64(%q) error = %v", value, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+)
+
+const testAdmin = "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg"
+
+// fakeFund 는 모든 호출에 response 를 돌려주는 펀드 레지스트리
+type fakeFund struct {
+	response sc.Response
+	args     []string // 마지막 호출 인자
+}
+
+func (f *fakeFund) Init(stub shim.ChaincodeStubInterface) sc.Response { return shim.Success(nil) }
+func (f *fakeFund) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
+	f.args = stub.GetStringArgs()
+	return f.response
+}
+
+func TestFundLookupFailsClosed(t *testing.T) {
+	fund := &fakeFund{}
+	stub := shim.NewMockStub("caller", nil)
+	stub.MockPeerChaincode(FundChaincodeName, shim.NewMockStub(FundChaincodeName, fund))
+
+	fund.response = shim.Success([]byte(testAdmin))
+	if admin, err := GetFundAdmin(stub, "F1"); err != nil || admin != testAdmin {
+		t.Errorf("GetFundAdmin = %s, %v", admin, err)
+	}
+
+	// 오류 응답의 payload 나 지갑주소가 아닌 응답을 관리자 주소로 쓰지 않는다.
+	for _, response := range []sc.Response{
+		model.ErrorResponse(model.NewCustomError(model.FundErrorType, "F1", "fund is not registered")),
+		{Status: shim.ERROR, Payload: []byte(testAdmin)},
+		shim.Success(nil),
+		shim.Success([]byte("admin")),
+	} {
+		fund.response = response
+		if admin, err := GetFundAdmin(stub, "F1"); err == nil || admin != "" {
+			t.Errorf("GetFundAdmin with %d %q = %s", response.Status, response.Payload, admin)
+		}
+	}
+
+	// IsFundAdmin 은 대표 관리자만, IsFundAdminOrAbove 는 admin 까지 확인한다.
+	fund.response = shim.Success([]byte("true"))
+	if ok, err := IsFundAdmin(stub, "F1", testAdmin); err != nil || !ok || fund.args[3] != model.FundRoleOwner {
+		t.Errorf("IsFundAdmin = %v, %v, %v", ok, err, fund.args)
+	}
+	if ok, err := IsFundAdminOrAbove(stub, "F1", testAdmin); err != nil || !ok || fund.args[3] != model.FundRoleAdmin {
+		t.Errorf("IsFundAdminOrAbove = %v, %v, %v", ok, err, fund.args)
+	}
+	for _, response := range []sc.Response{
+		model.ErrorResponse(model.NewCustomError(model.FundErrorType, "F1", "fund is not registered")),
+		{Status: shim.ERROR, Payload: []byte("true")},
+		shim.Success([]byte("yes")),
+	} {
+		fund.response = response
+		if ok, err := IsFundAdmin(stub, "F1", testAdmin); err == nil || ok {
+			t.Errorf("IsFundAdmin with %d %q = %v", response.Status, response.Payload, ok)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package wallet
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+)
+
+// 계정 레지스트리 키 (계정 주소 -> Account, 키 지갑주소 -> 계정 주소)
+const (
+	accountKeyType    = "walletAccount"
+	accountKeyKeyType = "walletAccountKey"
+)
+
+// MinRecoveryDelay 는 guardian 복구 대기 시간의 하한 (초).
+// 대기 시간은 블록 시간이 아니라 제출한 클라이언트가 정한 proposal timestamp 로 재므로
+// 키 소유자가 복구를 취소할 시간을 보장하지 않는다. proposal 시각을 미래로 정한 ExecuteRecovery 는 바로 통과할 수 있다.
+// 복구를 막으려면 guardian 을 믿을 수 있는 주소로 정하고, 승인이 보이면 바로 CancelRecovery 를 호출한다.
+const MinRecoveryDelay int64 = 3600
+
+// Account is 계정 레지스트리 항목
+// 계정 주소는 처음 등록한 키의 지갑주소로 고정되고, 서명 키는 교체하거나 guardian 으로 복구할 수 있다.
+type Account struct {
+	Address           string    `json:"address"`
+	Keys              []string  `json:"keys"` // 계정으로 서명할 수 있는 키 지갑주소
+	Guardians         []string  `json:"guardians,omitempty"`
+	GuardianThreshold int       `json:"guardianthreshold,omitempty"`
+	RecoveryDelay     int64     `json:"recoverydelay,omitempty"` // 초
+	Recovery          *Recovery `json:"recovery,omitempty"`
+}
+
+// Recovery is 진행 중인 guardian 복구
+type Recovery struct {
+	Key          string   `json:"key"` // 복구 후 서명 키 지갑주소
+	Approvals    []string `json:"approvals"`
+	ExecutableAt int64    `json:"executableat,omitempty"` // 승인이 threshold 에 도달한 뒤 실행 가능한 시각 (proposal timestamp 기준)
+}
+
+// AccountConfig is setupAccount 로 바꿀 수 있는 guardian 설정
+type AccountConfig struct {
+	Guardians         []string `json:"guardians"`
+	GuardianThreshold int      `json:"guardianthreshold"`
+	RecoveryDelay     int64    `json:"recoverydelay"`
+}
+
+// GetAccount 는 계정 레지스트리 항목을 반환한다. 등록되지 않았으면 nil 을 반환한다.
+func GetAccount(stub shim.ChaincodeStubInterface, address string) (*Account, error) {
+	key, err := accountKey(stub, accountKeyType, address)
+	if err != nil {
+		return nil, err
+	}
+	accountBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, model.NewCustomError(model.GetStateErrorType, key, err.Error())
+	}
+	if accountBytes == nil {
+		return nil, nil
+	}
+	account := Account{}
+	if err := json.Unmarshal(accountBytes, &account); err != nil {
+		return nil, model.NewCustomError(model.UnMarshalErrorType, key, err.Error())
+	}
+	return &account, nil
+}
+
+// SetupAccount 는 서명자의 계정을 만들거나 guardian 설정을 바꾼다.
+func SetupAccount(stub shim.ChaincodeStubInterface, walletParams *WalletParams, cfg AccountConfig) (*Account, error) {
+	account, created, err := signerAccount(stub, walletParams)
+	if err != nil {
+		return nil, err
+	}
+	if account.Recovery != nil {
+		return nil, model.NewCustomError(model.AccountErrorType, account.Address, "cancel pending recovery before changing guardians")
+	}
+
+	if len(cfg.Guardians) > 0 {
+		if cfg.GuardianThreshold < 1 || cfg.GuardianThreshold > len(cfg.Guardians) {
+			return nil, model.NewCustomError(model.MandatoryPrameterErrorType, "guardianthreshold", "guardianthreshold must be between 1 and number of guardians")
+		}
+		if cfg.RecoveryDelay < MinRecoveryDelay {
+			return nil, model.NewCustomError(model.MandatoryPrameterErrorType, "recoverydelay", "recoverydelay must be at least "+strconv.FormatInt(MinRecoveryDelay, 10)+" seconds")
+		}
+		seen := make(map[string]bool, len(cfg.Guardians))
+		for _, guardian := range cfg.Guardians {
+			if err := ValidateAddress(guardian); err != nil {
+				return nil, err
+			}
+			if guardian == account.Address || seen[guardian] {
+				return nil, model.NewCustomError(model.AddressErrorType, guardian, "guardian must be another, distinct address")
+			}
+			seen[guardian] = true
+		}
+	} else if cfg.GuardianThreshold != 0 || cfg.RecoveryDelay != 0 {
+		return nil, model.NewCustomError(model.MandatoryPrameterErrorType, "guardians", "guardianthreshold and recoverydelay require guardians")
+	}
+
+	account.Guardians = cfg.Guardians
+	account.GuardianThreshold = cfg.GuardianThreshold
+	account.RecoveryDelay = cfg.RecoveryDelay
+	if err := putAccount(stub, account); err != nil {
+		return nil, err
+	}
+	// 새로 만든 계정이면 처음 키를 계정에 매핑한다.
+	if created {
+		if err := putAccountKey(stub, account.Address, account.Address); err != nil {
+			return nil, err
+		}
+	}
+	return account, nil
+}
+
+// RotateKey 는 서명한 키를 newKey 로 교체한다. 교체된 키는 바로 계정으로 서명할 수 없다.
+// 진행 중인 guardian 복구는 취소되고, 계정이 등록한 위임 키는 모두 삭제된다.
+func RotateKey(stub shim.ChaincodeStubInterface, walletParams *WalletParams, newKey string) (*Account, error) {
+	account, _, err := signerAccount(stub, walletParams)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNewKey(stub, newKey); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(account.Keys))
+	for _, key := range account.Keys {
+		if key != walletParams.KeyAddress {
+			keys = append(keys, key)
+		}
+	}
+	account.Keys = append(keys, newKey)
+	account.Recovery = nil
+
+	if err := putAccount(stub, account); err != nil {
+		return nil, err
+	}
+	if err := delAccountKey(stub, walletParams.KeyAddress); err != nil {
+		return nil, err
+	}
+	if err := putAccountKey(stub, newKey, account.Address); err != nil {
+		return nil, err
+	}
+	if err := revokeDelegates(stub, account.Address); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// ApproveRecovery 는 guardian 의 복구 승인을 기록한다.
+// 승인이 threshold 에 도달하면 now + RecoveryDelay 부터 ExecuteRecovery 로 실행할 수 있다.
+// now 는 proposal timestamp 이므로 대기 시간은 원장 시간 기준으로 보장되지 않는다 (MinRecoveryDelay 참고).
+func ApproveRecovery(stub shim.ChaincodeStubInterface, guardian string, address string, newKey string, now int64) (*Account, error) {
+	account, err := requireAccount(stub, address)
+	if err != nil {
+		return nil, err
+	}
+	if !containsAddress(account.Guardians, guardian) {
+		return nil, model.NewCustomError(model.PermissionErrorType, guardian, "only account guardians can approve recovery")
+	}
+
+	recovery := account.Recovery
+	if recovery == nil {
+		if err := checkNewKey(stub, newKey); err != nil {
+			return nil, err
+		}
+		recovery = &Recovery{Key: newKey}
+	} else if recovery.Key != newKey {
+		return nil, model.NewCustomError(model.AccountErrorType, address, "another recovery is pending")
+	}
+	if containsAddress(recovery.Approvals, guardian) {
+		return nil, model.NewCustomError(model.AccountErrorType, guardian, "guardian already approved recovery")
+	}
+
+	recovery.Approvals = append(recovery.Approvals, guardian)
+	if len(recovery.Approvals) == account.GuardianThreshold {
+		recovery.ExecutableAt = now + account.RecoveryDelay
+	}
+	account.Recovery = recovery
+
+	if err := putAccount(stub, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// ExecuteRecovery 는 대기 시간이 지난 복구를 실행해서 계정의 서명 키를 복구 키 하나로 바꾼다.
+// 대기 시간은 호출한 클라이언트가 정한 now 로 확인한다.
+// 계정이 등록한 위임 키는 모두 삭제된다.
+func ExecuteRecovery(stub shim.ChaincodeStubInterface, address string, now int64) (*Account, error) {
+	account, err := requireAccount(stub, address)
+	if err != nil {
+		return nil, err
+	}
+	recovery := account.Recovery
+	if recovery == nil || len(recovery.Approvals) < account.GuardianThreshold {
+		return nil, model.NewCustomError(model.AccountErrorType, address, "recovery is not approved")
+	}
+	if now < recovery.ExecutableAt {
+		return nil, model.NewCustomError(model.AccountErrorType, address, "recovery is executable after "+strconv.FormatInt(recovery.ExecutableAt, 10))
+	}
+	// 승인 뒤 대기 시간 동안 복구 키가 다른 계정이나 위임 키로 등록되었을 수 있다.
+	if err := checkNewKey(stub, recovery.Key); err != nil {
+		return nil, err
+	}
+
+	for _, key := range account.Keys {
+		if err := delAccountKey(stub, key); err != nil {
+			return nil, err
+		}
+	}
+	account.Keys = []string{recovery.Key}
+	account.Recovery = nil
+
+	if err := putAccount(stub, account); err != nil {
+		return nil, err
+	}
+	if err := putAccountKey(stub, recovery.Key, account.Address); err != nil {
+		return nil, err
+	}
+	if err := revokeDelegates(stub, account.Address); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// CancelRecovery 는 계정 키로 진행 중인 복구를 취소한다.
+func CancelRecovery(stub shim.ChaincodeStubInterface, walletParams *WalletParams) (*Account, error) {
+	if err := RequireOwnKey(walletParams); err != nil {
+		return nil, err
+	}
+	if err := RequireCanonicalSig(walletParams); err != nil {
+		return nil, err
+	}
+	account, err := requireAccount(stub, walletParams.Address)
+	if err != nil {
+		return nil, err
+	}
+	if account.Recovery == nil {
+		return nil, model.NewCustomError(model.AccountErrorType, account.Address, "no pending recovery")
+	}
+	account.Recovery = nil
+	if err := putAccount(stub, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// resolveAccount 는 서명 키 지갑주소를 계정 레지스트리의 계정 주소로 바꾼다.
+// 계정에서 교체된 키는 자기 주소가 계정 주소이더라도 더 이상 서명할 수 없다.
+func resolveAccount(stub shim.ChaincodeStubInterface, walletParams *WalletParams) error {
+	keyAddr := walletParams.Address
+	walletParams.KeyAddress = keyAddr
+
+	address, err := getAccountKey(stub, keyAddr)
+	if err != nil {
+		return err
+	}
+	if address != "" {
+		walletParams.Address = address
+		return nil
+	}
+
+	account, err := GetAccount(stub, keyAddr)
+	if err != nil {
+		return err
+	}
+	if account != nil {
+		return model.NewCustomError(model.PermissionErrorType, keyAddr, "key is no longer authorised for account")
+	}
+	return nil
+}
+
+// signerAccount 는 서명자의 계정을 반환한다. 계정이 없으면 서명 키로 새 계정을 만들고 true 를 반환한다. (저장은 호출하는 쪽에서 한다)
+// 계정을 바꾸는 작업이므로 위임 키나 기존 방식 서명은 받지 않는다.
+func signerAccount(stub shim.ChaincodeStubInterface, walletParams *WalletParams) (*Account, bool, error) {
+	if err := RequireOwnKey(walletParams); err != nil {
+		return nil, false, err
+	}
+	if err := RequireCanonicalSig(walletParams); err != nil {
+		return nil, false, err
+	}
+	account, err := GetAccount(stub, walletParams.Address)
+	if err != nil {
+		return nil, false, err
+	}
+	if account == nil {
+		return &Account{Address: walletParams.Address, Keys: []string{walletParams.KeyAddress}}, true, nil
+	}
+	return account, false, nil
+}
+
+func requireAccount(stub shim.ChaincodeStubInterface, address string) (*Account, error) {
+	account, err := GetAccount(stub, address)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, model.NewCustomError(model.AccountErrorType, address, "account is not registered")
+	}
+	return account, nil
+}
+
+// checkNewKey 는 새 서명 키가 올바른 주소이고 다른 계정이나 위임 키로 쓰이지 않는지 확인한다.
+func checkNewKey(stub shim.ChaincodeStubInterface, newKey string) error {
+	if err := ValidateAddress(newKey); err != nil {
+		return err
+	}
+	address, err := getAccountKey(stub, newKey)
+	if err != nil {
+		return err
+	}
+	account, err := GetAccount(stub, newKey)
+	if err != nil {
+		return err
+	}
+	if address != "" || account != nil {
+		return model.NewCustomError(model.AccountErrorType, newKey, "key is already registered to an account")
+	}
+	delegation, err := GetDelegation(stub, newKey)
+	if err != nil {
+		return err
+	}
+	if delegation != nil {
+		return model.NewCustomError(model.AccountErrorType, newKey, "key is registered as a delegate key")
+	}
+	return nil
+}
+
+func containsAddress(addresses []string, address string) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+func putAccount(stub shim.ChaincodeStubInterface, account *Account) error {
+	key, err := accountKey(stub, accountKeyType, account.Address)
+	if err != nil {
+		return err
+	}
+	accountBytes, err := json.Marshal(account)
+	if err != nil {
+		return model.NewCustomError(model.MarshalErrorType, "Account", err.Error())
+	}
+	if err := stub.PutState(key, accountBytes); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	return nil
+}
+
+func getAccountKey(stub shim.ChaincodeStubInterface, keyAddr string) (string, error) {
+	key, err := accountKey(stub, accountKeyKeyType, keyAddr)
+	if err != nil {
+		return "", err
+	}
+	value, err := stub.GetState(key)
+	if err != nil {
+		return "", model.NewCustomError(model.GetStateErrorType, key, err.Error())
+	}
+	return string(value), nil
+}
+
+func putAccountKey(stub shim.ChaincodeStubInterface, keyAddr string, address string) error {
+	key, err := accountKey(stub, accountKeyKeyType, keyAddr)
+	if err != nil {
+		return err
+	}
+	if err := stub.PutState(key, []byte(address)); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	return nil
+}
+
+func delAccountKey(stub shim.ChaincodeStubInterface, keyAddr string) error {
+	key, err := accountKey(stub, accountKeyKeyType, keyAddr)
+	if err != nil {
+		return err
+	}
+	if err := stub.DelState(key); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	return nil
+}
+
+func accountKey(stub shim.ChaincodeStubInterface, keyType string, address string) (string, error) {
+	key, err := stub.CreateCompositeKey(keyType, []string{address})
+	if err != nil {
+		return "", model.NewCustomError(model.CreateCompositeKeyErrorType, keyType, err.Error())
+	}
+	return key, nil
+}
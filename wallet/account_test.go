@@ -0,0 +1,87 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+)
+
+func TestExecuteRecoveryAfterDelay(t *testing.T) {
+	stub := shim.NewMockStub("wallet", nil)
+	accountAddr, g1, g2, newKey := "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg", "uEHhePkzGgQmsAndX9MQTvviBMGNdF3YP9", "uUjYR1DtbzXbD6cWN4qgrwTwnd7ShTgGZ1", "tcccaeH7kVa9qZQANJt7fC7gxNj3ARTPKk"
+	const now = 1700000000
+
+	stub.MockTransactionStart("setup")
+	defer stub.MockTransactionEnd("setup")
+	signer := &WalletParams{Address: accountAddr, KeyAddress: accountAddr, Sigver: SigVerSHA256}
+	if _, err := SetupAccount(stub, signer, AccountConfig{Guardians: []string{g1, g2}, GuardianThreshold: 2, RecoveryDelay: MinRecoveryDelay}); err != nil {
+		t.Fatal(err)
+	}
+	// 복구된 계정에서는 잃어버린 키로 등록한 위임 키도 쓸 수 없어야 한다.
+	if _, err := RegisterDelegate(stub, signer, DelegateConfig{Delegate: g1, Expiry: now + 2*MinRecoveryDelay, Functions: []string{"transfer"}, SpendLimit: 10}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ApproveRecovery(stub, g1, accountAddr, newKey, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExecuteRecovery(stub, accountAddr, now+MinRecoveryDelay); err == nil {
+		t.Error("recovery executed below threshold")
+	}
+	account, err := ApproveRecovery(stub, g2, accountAddr, newKey, now+10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Recovery.ExecutableAt != now+10+MinRecoveryDelay {
+		t.Errorf("executableat %d", account.Recovery.ExecutableAt)
+	}
+
+	if _, err := ExecuteRecovery(stub, accountAddr, now+10+MinRecoveryDelay-1); err == nil {
+		t.Error("recovery executed before delay")
+	}
+	if _, err := ExecuteRecovery(stub, accountAddr, now+10+MinRecoveryDelay); err != nil {
+		t.Fatal(err)
+	}
+
+	recoveredKey := &WalletParams{Address: newKey}
+	if err := resolveAccount(stub, recoveredKey); err != nil || recoveredKey.Address != accountAddr {
+		t.Errorf("recovered key resolves to %s, err %v", recoveredKey.Address, err)
+	}
+	oldKey := &WalletParams{Address: accountAddr}
+	if customErr := model.ToCustomError(resolveAccount(stub, oldKey)); customErr == nil || customErr.ErrorType != model.PermissionErrorType {
+		t.Errorf("old key after recovery: %v", customErr)
+	}
+	if delegation, err := GetDelegation(stub, g1); err != nil || delegation != nil {
+		t.Errorf("delegate after recovery: %+v, err %v", delegation, err)
+	}
+}
+
+func TestExecuteRecoveryRechecksKey(t *testing.T) {
+	stub := shim.NewMockStub("wallet", nil)
+	accountAddr, guardian, newKey, other := "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg", "uEHhePkzGgQmsAndX9MQTvviBMGNdF3YP9", "tcccaeH7kVa9qZQANJt7fC7gxNj3ARTPKk", "uUjYR1DtbzXbD6cWN4qgrwTwnd7ShTgGZ1"
+	const now = 1700000000
+
+	stub.MockTransactionStart("recheck")
+	defer stub.MockTransactionEnd("recheck")
+	signer := &WalletParams{Address: accountAddr, KeyAddress: accountAddr, Sigver: SigVerSHA256}
+	if _, err := SetupAccount(stub, signer, AccountConfig{Guardians: []string{guardian}, GuardianThreshold: 1, RecoveryDelay: MinRecoveryDelay}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ApproveRecovery(stub, guardian, accountAddr, newKey, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// 대기 시간 동안 복구 키가 다른 지갑의 위임 키로 등록되면 복구를 실행할 수 없다.
+	otherSigner := &WalletParams{Address: other, KeyAddress: other, Sigver: SigVerSHA256}
+	if _, err := RegisterDelegate(stub, otherSigner, DelegateConfig{Delegate: newKey, Expiry: now + 2*MinRecoveryDelay, Functions: []string{"transfer"}, SpendLimit: 10}, now); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ExecuteRecovery(stub, accountAddr, now+MinRecoveryDelay)
+	if customErr := model.ToCustomError(err); customErr == nil || customErr.ErrorType != model.AccountErrorType {
+		t.Errorf("recovery to delegate key: %v", customErr)
+	}
+	if key, err := getAccountKey(stub, newKey); err != nil || key != "" {
+		t.Errorf("recovery key mapped to %q, err %v", key, err)
+	}
+}
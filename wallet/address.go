@@ -0,0 +1,219 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/jinsan74/Erc20/model"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// legacyAddress 는 공개키 문자열로 기존 형식의 지갑주소를 만든다.
+// 체크섬이 없고 앞의 0 이 사라지는 형식이므로 기존 잔액 매핑에만 사용한다.
+func legacyAddress(publicKeyStr string) (string, error) {
+
+	//--Double Hash 처리---------------------------
+	publicKeyByte1 := []byte(publicKeyStr)
+	publicKeyHash1 := sha256.Sum256(publicKeyByte1)
+
+	publicKeyStr2 := fmt.Sprintf("%x", publicKeyHash1)
+	publicKeyByte2 := []byte(publicKeyStr2)
+	publicKeyHash2 := sha256.Sum256(publicKeyByte2)
+
+	//--Public Key SAH256 처리----
+	shaPubKey := fmt.Sprintf("%x", publicKeyHash2)
+
+	//--RIPEMD160 로직 추가---
+	h := ripemd160.New()
+	h.Write([]byte(shaPubKey))
+	shaPubKey = fmt.Sprintf("%x", h.Sum(nil))
+	//----------------------
+
+	//--Public Key SAH256 => BASE58Check 처리 ----
+	shaPubkeyStr := new(big.Int)
+	shaPubkeyStr.SetString(shaPubKey, 32)
+
+	shaPubkeyDigit := fmt.Sprint(shaPubkeyStr)
+
+	walletAddr, err := convertToBase58(shaPubkeyDigit, 10)
+	if err != nil {
+		return "", model.NewCustomError(model.ConvertErrorType, "address", err.Error())
+	}
+	return walletAddr, nil
+}
+
+// versionedAddress 는 version + RIPEMD160(SHA256(rawKey)) 를 Base58Check 로 인코딩한다.
+func versionedAddress(version byte, rawKey []byte) string {
+	keyHash := sha256.Sum256(rawKey)
+	h := ripemd160.New()
+	h.Write(keyHash[:])
+	return base58CheckEncode(version, h.Sum(nil))
+}
+
+// base58CheckEncode 는 version + payload + 4바이트 double-SHA256 체크섬을 Base58 로 인코딩한다.
+func base58CheckEncode(version byte, payload []byte) string {
+	data := make([]byte, 0, 1+len(payload)+4)
+	data = append(data, version)
+	data = append(data, payload...)
+
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	data = append(data, second[:4]...)
+
+	return encodeBase58(data)
+}
+
+// decodeBase58 은 encodeBase58 의 역변환. 앞의 '1' 은 0 바이트로 복원한다.
+func decodeBase58(encoded string) ([]byte, bool) {
+	x := new(big.Int)
+	for i := 0; i < len(encoded); i++ {
+		digit := strings.IndexByte(alphabet, encoded[i])
+		if digit < 0 {
+			return nil, false
+		}
+		x.Mul(x, big58)
+		x.Add(x, big.NewInt(int64(digit)))
+	}
+
+	zeros := 0
+	for zeros < len(encoded) && encoded[zeros] == alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), x.Bytes()...), true
+}
+
+// encodeBase58 은 앞의 0 바이트를 '1' 로 보존하는 Base58 인코딩
+func encodeBase58(data []byte) string {
+	x := new(big.Int).SetBytes(data)
+	var encoded []byte
+	rem := new(big.Int)
+	for x.Cmp(big0) == 1 {
+		x.QuoRem(x, big58, rem)
+		encoded = append(encoded, alphabet[rem.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		encoded = append(encoded, alphabet[0])
+	}
+	return reverse(string(encoded))
+}
+
+// compressedKey 는 SEC1 압축 공개키 (0x02/0x03 + X)
+func compressedKey(pub *ecdsa.PublicKey) []byte {
+	byteLen := (pub.Curve.Params().BitSize + 7) / 8
+	raw := make([]byte, 1+byteLen)
+	raw[0] = 0x02 + byte(pub.Y.Bit(0))
+	xBytes := pub.X.Bytes()
+	copy(raw[1+byteLen-len(xBytes):], xBytes)
+	return raw
+}
+
+// walletAddress 는 곡선의 주소 버전에 맞는 지갑주소를 만든다.
+func walletAddress(pub *ecdsa.PublicKey, spec curveSpec) string {
+	return versionedAddress(spec.addrVersion, compressedKey(pub))
+}
+
+// addressVersions 는 발급하는 주소 버전 바이트와 키 종류
+var addressVersions = map[byte]string{
+	AddressVersionP256:      CurveP256,
+	AddressVersionSecp256k1: CurveSecp256k1,
+	AddressVersionEd25519:   KeyTypeEd25519,
+	AddressVersionMultisig:  KeyTypeMultisig,
+}
+
+// addressHashLen 은 RIPEMD160 해시 길이
+const addressHashLen = 20
+
+// ZeroAddress 는 발행/소각 Transfer 이벤트의 상대 주소 (ERC-20 의 0 주소)
+// 버전 0x00 과 0 으로 채운 키 해시의 Base58Check 인코딩이다. 0x00 은 발급하는 버전이 아니므로 ValidateAddress 를 통과하지 않고
+// 어떤 키로도 서명하거나 토큰을 받을 수 없다.
+const ZeroAddress = "1111111111111111111114oLvT2"
+
+// DecodeAddress 는 Base58Check 지갑주소를 버전 바이트와 키 해시로 디코딩한다.
+// 체크섬이 틀리거나 등록되지 않은 버전이면 AddressErrorType 오류를 반환한다. 기존 형식 주소는 받지 않는다.
+func DecodeAddress(address string) (byte, []byte, error) {
+	data, ok := decodeBase58(address)
+	if !ok {
+		return 0, nil, model.NewCustomError(model.AddressErrorType, address, "address is not base58")
+	}
+	if len(data) != 1+addressHashLen+4 {
+		return 0, nil, model.NewCustomError(model.AddressErrorType, address, "address has invalid length")
+	}
+
+	body, checksum := data[:len(data)-4], data[len(data)-4:]
+	first := sha256.Sum256(body)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		return 0, nil, model.NewCustomError(model.AddressErrorType, address, "address checksum mismatch")
+	}
+
+	version := body[0]
+	if _, ok := addressVersions[version]; !ok {
+		return 0, nil, model.NewCustomError(model.AddressErrorType, address, fmt.Sprintf("unknown address version 0x%02x", version))
+	}
+	return version, body[1:], nil
+}
+
+// ValidateAddress 는 address 가 올바른 Base58Check 지갑주소인지 확인한다.
+func ValidateAddress(address string) error {
+	_, _, err := DecodeAddress(address)
+	return err
+}
+
+// 기존 형식 주소의 길이 범위. RIPEMD160 hex 를 32진수로 읽은 200비트 미만의 값을 Base58 로 쓴 자릿수이다.
+const (
+	legacyAddressMinLen = 30
+	legacyAddressMaxLen = 35
+)
+
+// ValidateLegacyAddress 는 address 가 기존 형식 지갑주소로 쓸 수 있는 문자열인지 확인한다.
+// 기존 형식은 체크섬이 없어서 오타를 찾을 수 없으므로 Base58 문자와 길이만 확인한다.
+// 기존 형식 주소를 명시적으로 받는 곳에서만 사용한다.
+func ValidateLegacyAddress(address string) error {
+	if len(address) < legacyAddressMinLen || len(address) > legacyAddressMaxLen {
+		return model.NewCustomError(model.AddressErrorType, address, "legacy address has invalid length")
+	}
+	if _, ok := decodeBase58(address); !ok {
+		return model.NewCustomError(model.AddressErrorType, address, "legacy address is not base58")
+	}
+	return nil
+}
+
+// AddressType 은 주소 버전 바이트의 키 종류 (곡선 식별자, KeyTypeEd25519 또는 KeyTypeMultisig) 를 반환한다.
+func AddressType(version byte) string {
+	return addressVersions[version]
+}
+
+// AddressFromPublicKey 는 WalletMeta 와 같은 형식의 공개키로 지갑주소를 만든다.
+// curve, keytype 이 비어 있으면 P-256 ECDSA 로 본다.
+func AddressFromPublicKey(publicKeyStr string, curve string, keytype string) (string, error) {
+	switch keytype {
+	case "", KeyTypeECDSA:
+		spec, err := lookupCurve(curve)
+		if err != nil {
+			return "", err
+		}
+		pub, err := parseECDSAPublicKey(publicKeyStr, spec)
+		if err != nil {
+			return "", err
+		}
+		return walletAddress(pub, spec), nil
+	case KeyTypeEd25519:
+		if curve != "" {
+			return "", model.NewCustomError(model.PublicKeyErrorType, "curve", "curve must be empty for keytype "+KeyTypeEd25519)
+		}
+		pub, err := parseEd25519PublicKey(publicKeyStr)
+		if err != nil {
+			return "", err
+		}
+		return versionedAddress(AddressVersionEd25519, pub), nil
+	default:
+		return "", model.NewCustomError(model.PublicKeyErrorType, "keytype", "unsupported keytype "+keytype)
+	}
+}
@@ -0,0 +1,12 @@
+package wallet
+
+import "testing"
+
+func TestZeroAddress(t *testing.T) {
+	if want := base58CheckEncode(0x00, make([]byte, addressHashLen)); ZeroAddress != want {
+		t.Errorf("ZeroAddress = %s, want %s", ZeroAddress, want)
+	}
+	if err := ValidateAddress(ZeroAddress); err == nil {
+		t.Error("ZeroAddress accepted as wallet address")
+	}
+}
@@ -3,8 +3,11 @@ package wallet
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
 )
 
 // TransferMeta is Multi Transfer를 이용하기 위한 데이터 구조체
@@ -13,40 +16,150 @@ type TransferMeta struct {
 	Amount  uint64 `json:"amount,omitempty"`
 }
 
+// Validate 는 받는 지갑주소가 올바른지 확인한다.
+func (t TransferMeta) Validate() error {
+	return ValidateAddress(t.Address)
+}
+
 type TransferMetaN struct {
 	FromAddress string `json:"fromaddress"`
 	ToAddress   string `json:"toaddress"`
 	Amount      uint64 `json:"amount,omitempty"`
 }
 
+// Validate 는 받는 지갑주소가 올바른지 확인한다.
+func (t TransferMetaN) Validate() error {
+	return ValidateAddress(t.ToAddress)
+}
+
 // WalletMeta is 지갑 데이터 구조체
 type WalletMeta struct {
-	Publickey  string `json:"publickey,omitempty"`
-	Txtime     string `json:"txtime,omitempty"`
-	Nowtime    int64  `json:"nowtime,omitempty"`
-	Transdata  string `json:"transdata,omitempty"`
-	Transjdata string `json:"transjdata,omitempty"`
-	Sigmsg     string `json:"sigmsg,omitempty"`
+	Publickey  string      `json:"publickey,omitempty"`
+	Txtime     string      `json:"txtime,omitempty"`
+	Nowtime    int64       `json:"nowtime,omitempty"`
+	Transdata  string      `json:"transdata,omitempty"`
+	Transjdata string      `json:"transjdata,omitempty"`
+	Sigmsg     string      `json:"sigmsg,omitempty"`
+	Sigver     string      `json:"sigver,omitempty"`
+	Nonce      string      `json:"nonce,omitempty"`
+	Curve      string      `json:"curve,omitempty"`
+	Keytype    string      `json:"keytype,omitempty"`
+	Sigs       []SignerSig `json:"sigs,omitempty"`
+}
+
+// ParseWalletMeta 는 지갑 파라미터 JSON 을 엄격하게 디코딩한다.
+// 알 수 없는 필드나 JSON 뒤의 추가 데이터가 있으면 거부한다.
+func ParseWalletMeta(param string) (WalletMeta, error) {
+	walletMeta := WalletMeta{}
+
+	decoder := json.NewDecoder(strings.NewReader(param))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&walletMeta); err != nil {
+		return walletMeta, model.NewCustomError(model.UnMarshalErrorType, "WalletMeta", err.Error())
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return walletMeta, model.NewCustomError(model.UnMarshalErrorType, "WalletMeta", "unexpected data after wallet parameter")
+	}
+	return walletMeta, nil
+}
+
+// WalletParams is 서명 검증을 통과한 지갑 트랜잭션 파라미터
+type WalletParams struct {
+	Address       string      // 서명자 지갑주소 (계정 레지스트리에 등록된 키이면 계정 주소)
+	KeyAddress    string      // 서명 키 지갑주소
+	LegacyAddress string      // 같은 키의 기존 형식 지갑주소, 없으면 ""
+	Publickey     string      // 서명에 사용된 공개키 (multisig 이면 multisig 지갑주소)
+	Signers       []string    // multisig 서명에 참여한 멤버 지갑주소
+	Sigver        string      // 서명 방식 버전 (WalletMeta.Sigver)
+	Delegation    *Delegation // 위임 키로 서명했으면 위임 항목, 아니면 nil
+	Nonce         string      // 소모한 nonce, 없으면 "" (nonce 는 canonical 서명에만 쓸 수 있다)
+	Params        []string    // transdata 를 ',' 로 나눈 위치 파라미터
+	Jdata         string      // transjdata (JSON)
+}
+
+// Signer is verifySigner 로 확인한 서명자 (다른 체인코드가 토큰 체인코드로 서명자를 확인할 때 사용)
+type Signer struct {
+	Address       string `json:"address"`                 // 계정 주소 또는 서명 키 지갑주소
+	KeyAddress    string `json:"keyaddress"`              // 서명 키 지갑주소
+	LegacyAddress string `json:"legacyaddress,omitempty"` // 같은 키의 기존 형식 지갑주소
 }
 
 // CallVaildWallet is vaildWallet 호출 함수
-func CallVaildWallet(stub shim.ChaincodeStubInterface) []string {
+// 실패하면 오류 종류를 담은 CustomError 를 반환한다. model.ErrorResponse 로 응답을 만들 수 있다.
+func CallVaildWallet(stub shim.ChaincodeStubInterface) (*WalletParams, *model.CustomError) {
 
-	_, orgParam := stub.GetFunctionAndParameters()
+	walletParams, err := callVaildWallet(stub)
+	if err != nil {
+		fmt.Println("Call VaildWallet ERR...", err.Error())
+		return nil, model.ToCustomError(err)
+	}
+	return walletParams, nil
+}
+
+func callVaildWallet(stub shim.ChaincodeStubInterface) (*WalletParams, error) {
 
-	nowTimestamp, _ := stub.GetTxTimestamp()
+	function, orgParam := stub.GetFunctionAndParameters()
+	if len(orgParam) != 1 {
+		return nil, model.NewCustomError(model.MandatoryPrameterErrorType, "Wallet Parameter", "expecting exactly one wallet parameter")
+	}
+
+	nowTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, model.NewCustomError(model.TxTimeStampErrorType, "TxTimestamp", err.Error())
+	}
 	nowTime := nowTimestamp.GetSeconds()
 
-	walletMeta := WalletMeta{}
-	json.Unmarshal([]byte(orgParam[0]), &walletMeta)
+	walletMeta, err := ParseWalletMeta(orgParam[0])
+	if err != nil {
+		return nil, err
+	}
 	walletMeta.Nowtime = nowTime
 
-	retParams, err := vaildWallet(walletMeta)
+	sigCtx, err := NewSigContext(stub)
+	if err != nil {
+		return nil, err
+	}
 
+	txTimeCfg, err := GetTxTimeConfig(stub)
 	if err != nil {
-		fmt.Println("Call VaildWallet ERR...", err.Error())
-		return nil
+		return nil, err
+	}
+
+	sigCfg, err := GetSigConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy *MultisigPolicy
+	if walletMeta.Keytype == KeyTypeMultisig {
+		if policy, err = GetMultisig(stub, walletMeta.Publickey); err != nil {
+			return nil, err
+		}
+	}
+
+	walletParams, err := vaildWallet(walletMeta, sigCtx, txTimeCfg, sigCfg, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	// 서명 키를 위임한 지갑이나 계정 주소로 바꾼다.
+	delegated, err := resolveDelegate(stub, walletParams, function, nowTime)
+	if err != nil {
+		return nil, err
+	}
+	if !delegated {
+		if err := resolveAccount(stub, walletParams); err != nil {
+			return nil, err
+		}
+	}
+
+	// 서명이 확인된 뒤에 nonce 를 소모한다.
+	if walletMeta.Nonce != "" {
+		if err := useNonce(stub, walletParams.Address, walletParams.LegacyAddress, walletMeta.Nonce); err != nil {
+			return nil, err
+		}
+		walletParams.Nonce = walletMeta.Nonce
 	}
 
-	return retParams
+	return walletParams, nil
 }
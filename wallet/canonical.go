@@ -0,0 +1,115 @@
+package wallet
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/jinsan74/Erc20/model"
+)
+
+// 서명 방식 버전 (WalletMeta.Sigver)
+const (
+	// SigVerLegacy 는 publickey + txtime 만 서명하는 기존 방식 (마이그레이션 기간에만 사용)
+	SigVerLegacy = "1"
+	// SigVerCanonical 은 호출 정보와 트랜잭션 데이터를 모두 서명하는 방식 (ECDSA digest 는 SHA-1)
+	SigVerCanonical = "2"
+	// SigVerSHA256 은 SigVerCanonical 과 같은 메시지를 SHA-256 digest 로 서명하는 방식
+	SigVerSHA256 = "3"
+)
+
+// SigContext is 서명 메시지에 포함되는 호출 정보
+// Function, Chaincode 는 클라이언트가 제출한 proposal 기준이고
+// ExecFunction, ExecChaincode 는 서명을 검증하는 체인코드와 그 체인코드에서 실행 중인 함수이다.
+// 다른 체인코드를 거쳐 전달된 봉투는 실행되는 함수까지 서명해야 하므로 다른 함수로 바꿔 전달할 수 없다.
+type SigContext struct {
+	Function      string
+	Channel       string
+	Chaincode     string
+	ExecFunction  string
+	ExecChaincode string
+}
+
+// forwarded 는 다른 체인코드를 거쳐 전달된 호출인지 확인한다.
+func (ctx SigContext) forwarded() bool {
+	return ctx.ExecFunction != ctx.Function || ctx.ExecChaincode != ctx.Chaincode
+}
+
+// chaincodeIDEnv 는 peer 가 체인코드 프로세스에 넘겨주는 체인코드 ID ("이름:버전")
+const chaincodeIDEnv = "CORE_CHAINCODE_ID_NAME"
+
+// ChaincodeName 은 실행 중인 체인코드 이름. peer 가 설정한 CORE_CHAINCODE_ID_NAME 에서 읽는다.
+func ChaincodeName() string {
+	return strings.SplitN(os.Getenv(chaincodeIDEnv), ":", 2)[0]
+}
+
+// NewSigContext 는 stub 의 signed proposal 과 실행 중인 함수로 서명 컨텍스트를 만든다.
+func NewSigContext(stub shim.ChaincodeStubInterface) (SigContext, error) {
+
+	function, _ := stub.GetFunctionAndParameters()
+	ctx := SigContext{Channel: stub.GetChannelID(), ExecFunction: function, ExecChaincode: ChaincodeName()}
+
+	signedProp, err := stub.GetSignedProposal()
+	if err != nil {
+		return ctx, model.NewCustomError(model.UnMarshalErrorType, "SignedProposal", err.Error())
+	}
+	if signedProp == nil {
+		return ctx, nil
+	}
+
+	prop := &pb.Proposal{}
+	if err := proto.Unmarshal(signedProp.ProposalBytes, prop); err != nil {
+		return ctx, model.NewCustomError(model.UnMarshalErrorType, "Proposal", err.Error())
+	}
+	payload := &pb.ChaincodeProposalPayload{}
+	if err := proto.Unmarshal(prop.Payload, payload); err != nil {
+		return ctx, model.NewCustomError(model.UnMarshalErrorType, "ChaincodeProposalPayload", err.Error())
+	}
+	cis := &pb.ChaincodeInvocationSpec{}
+	if err := proto.Unmarshal(payload.Input, cis); err != nil {
+		return ctx, model.NewCustomError(model.UnMarshalErrorType, "ChaincodeInvocationSpec", err.Error())
+	}
+
+	spec := cis.GetChaincodeSpec()
+	ctx.Chaincode = spec.GetChaincodeId().GetName()
+	if args := spec.GetInput().GetArgs(); len(args) > 0 {
+		ctx.Function = string(args[0])
+	}
+	return ctx, nil
+}
+
+// CanonicalMessage 는 SigVerCanonical, SigVerSHA256 서명 대상 메시지를 만든다.
+// 각 필드를 "<바이트 길이>:<값>" 으로 이어 붙여 필드 경계가 모호하지 않게 한다.
+// 전달된 호출이면 proposal 의 체인코드 뒤에 실행 중인 체인코드와 함수를 더한다.
+// 직접 호출은 두 값이 proposal 과 같으므로 필드를 더하지 않고, 필드 수가 다른 두 메시지는 같아질 수 없다.
+func CanonicalMessage(walletMeta WalletMeta, ctx SigContext) string {
+	fields := []string{
+		walletMeta.Sigver,
+		ctx.Function,
+		ctx.Channel,
+		ctx.Chaincode,
+	}
+	if ctx.forwarded() {
+		fields = append(fields, ctx.ExecChaincode, ctx.ExecFunction)
+	}
+	fields = append(fields,
+		walletMeta.Publickey,
+		walletMeta.Transdata,
+		walletMeta.Transjdata,
+		walletMeta.Txtime,
+		walletMeta.Nonce,
+		walletMeta.Curve,
+		walletMeta.Keytype,
+	)
+
+	var sb strings.Builder
+	for _, field := range fields {
+		sb.WriteString(strconv.Itoa(len(field)))
+		sb.WriteByte(':')
+		sb.WriteString(field)
+	}
+	return sb.String()
+}
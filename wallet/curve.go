@@ -0,0 +1,53 @@
+package wallet
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/jinsan74/Erc20/model"
+)
+
+// 곡선 식별자 (WalletMeta.Curve), 비어 있으면 CurveP256
+const (
+	CurveP256      = "p256"
+	CurveSecp256k1 = "secp256k1"
+)
+
+// 주소 버전 바이트
+// 0x7C 이상이면 Base58Check 값이 legacy 주소의 최대값(약 2^199)보다 항상 크므로 두 형식은 겹치지 않는다.
+const (
+	AddressVersionP256      byte = 0x80
+	AddressVersionSecp256k1 byte = 0x81
+)
+
+// curveSpec is 곡선 레지스트리 항목
+type curveSpec struct {
+	curve elliptic.Curve
+	// a 는 곡선식 y² = x³ + ax + b 의 계수 (압축 공개키 복원용)
+	a int64
+	// oid 는 SubjectPublicKeyInfo 의 namedCurve
+	oid         asn1.ObjectIdentifier
+	addrVersion byte
+	// legacySig 가 false 이면 SigVerLegacy 서명을 받지 않는다.
+	legacySig bool
+	// legacyAddr 가 true 이면 기존 형식 주소도 함께 계산해서 매핑한다.
+	legacyAddr bool
+}
+
+var curveRegistry = map[string]curveSpec{
+	CurveP256:      {curve: elliptic.P256(), a: -3, oid: asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}, addrVersion: AddressVersionP256, legacySig: true, legacyAddr: true},
+	CurveSecp256k1: {curve: secp256k1.S256(), a: 0, oid: asn1.ObjectIdentifier{1, 3, 132, 0, 10}, addrVersion: AddressVersionSecp256k1},
+}
+
+// lookupCurve 는 곡선 식별자에 해당하는 레지스트리 항목을 반환한다.
+func lookupCurve(curveID string) (curveSpec, error) {
+	if curveID == "" {
+		curveID = CurveP256
+	}
+	spec, ok := curveRegistry[curveID]
+	if !ok {
+		return spec, model.NewCustomError(model.PublicKeyErrorType, "curve", "unsupported curve "+curveID)
+	}
+	return spec, nil
+}
@@ -0,0 +1,258 @@
+package wallet
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+)
+
+// 위임 키 레지스트리 키 (위임 키 지갑주소 -> Delegation, 위임한 지갑주소 + 위임 키 지갑주소 -> 색인)
+const (
+	delegateKeyType       = "walletDelegate"
+	delegateParentKeyType = "walletDelegateParent"
+)
+
+// MaxDelegateFunctions 는 위임 키 하나에 허용할 수 있는 함수 수의 상한
+const MaxDelegateFunctions = 32
+
+// Delegation is 위임 키 등록 항목
+// 위임 키의 서명은 Parent 지갑의 서명으로 처리되지만, Expiry 전에 Functions 에 있는 함수만 호출할 수 있고
+// 보낼 수 있는 토큰 합계는 SpendLimit 을 넘을 수 없다.
+//
+// Expiry 는 블록 시간이 아니라 제출한 클라이언트가 정한 proposal timestamp 와 비교하므로 권고 값일 뿐이다.
+// proposal 시각을 과거로 정한 트랜잭션은 만료 뒤에도 통과할 수 있으므로 위임 키를 확실히 막으려면 RevokeDelegate 로 회수한다.
+type Delegation struct {
+	Delegate   string   `json:"delegate"`
+	Parent     string   `json:"parent"`
+	Expiry     int64    `json:"expiry"` // proposal timestamp(초)가 이 시각 이상이면 서명할 수 없다 (권고)
+	Functions  []string `json:"functions"`
+	SpendLimit uint64   `json:"spendlimit"`
+	Spent      uint64   `json:"spent"`
+}
+
+// DelegateConfig is registerDelegate 로 등록하는 위임 설정
+type DelegateConfig struct {
+	Delegate   string   `json:"delegate"`
+	Expiry     int64    `json:"expiry"`
+	Functions  []string `json:"functions"`
+	SpendLimit uint64   `json:"spendlimit"`
+}
+
+// GetDelegation 은 위임 키 등록 항목을 반환한다. 등록되지 않았으면 nil 을 반환한다.
+func GetDelegation(stub shim.ChaincodeStubInterface, delegate string) (*Delegation, error) {
+	key, err := accountKey(stub, delegateKeyType, delegate)
+	if err != nil {
+		return nil, err
+	}
+	delegationBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, model.NewCustomError(model.GetStateErrorType, key, err.Error())
+	}
+	if delegationBytes == nil {
+		return nil, nil
+	}
+	delegation := Delegation{}
+	if err := json.Unmarshal(delegationBytes, &delegation); err != nil {
+		return nil, model.NewCustomError(model.UnMarshalErrorType, key, err.Error())
+	}
+	return &delegation, nil
+}
+
+// RegisterDelegate 는 서명자 지갑의 위임 키를 등록한다. 같은 지갑이 다시 등록하면 설정과 사용량을 새로 쓴다.
+// now 는 proposal timestamp 이므로 Expiry 는 원장 시간 기준의 만료를 보장하지 않는다 (Delegation 참고).
+func RegisterDelegate(stub shim.ChaincodeStubInterface, walletParams *WalletParams, cfg DelegateConfig, now int64) (*Delegation, error) {
+	if err := RequireOwnKey(walletParams); err != nil {
+		return nil, err
+	}
+	if err := RequireCanonicalSig(walletParams); err != nil {
+		return nil, err
+	}
+	if err := ValidateAddress(cfg.Delegate); err != nil {
+		return nil, err
+	}
+	if cfg.Delegate == walletParams.Address || cfg.Delegate == walletParams.KeyAddress {
+		return nil, model.NewCustomError(model.DelegateErrorType, cfg.Delegate, "delegate must differ from signing wallet")
+	}
+	if cfg.Expiry <= now {
+		return nil, model.NewCustomError(model.MandatoryPrameterErrorType, "expiry", "expiry must be after transaction time")
+	}
+	if len(cfg.Functions) == 0 || len(cfg.Functions) > MaxDelegateFunctions {
+		return nil, model.NewCustomError(model.MandatoryPrameterErrorType, "functions", "functions must list 1 to "+strconv.Itoa(MaxDelegateFunctions)+" functions")
+	}
+	for _, function := range cfg.Functions {
+		if function == "" {
+			return nil, model.NewCustomError(model.MandatoryPrameterErrorType, "functions", "function name is empty")
+		}
+	}
+
+	current, err := GetDelegation(stub, cfg.Delegate)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && current.Parent != walletParams.Address {
+		return nil, model.NewCustomError(model.DelegateErrorType, cfg.Delegate, "key is already delegated by another wallet")
+	}
+	if current == nil {
+		if err := checkNewKey(stub, cfg.Delegate); err != nil {
+			return nil, err
+		}
+	}
+
+	delegation := &Delegation{
+		Delegate:   cfg.Delegate,
+		Parent:     walletParams.Address,
+		Expiry:     cfg.Expiry,
+		Functions:  cfg.Functions,
+		SpendLimit: cfg.SpendLimit,
+	}
+	if err := putDelegation(stub, delegation); err != nil {
+		return nil, err
+	}
+	if err := putDelegateIndex(stub, delegation.Parent, delegation.Delegate); err != nil {
+		return nil, err
+	}
+	return delegation, nil
+}
+
+// RevokeDelegate 는 서명자 지갑의 위임 키를 삭제한다. 다음 트랜잭션부터 위임 키의 서명은 지갑 서명으로 처리되지 않는다.
+func RevokeDelegate(stub shim.ChaincodeStubInterface, walletParams *WalletParams, delegate string) error {
+	if err := RequireCanonicalSig(walletParams); err != nil {
+		return err
+	}
+	delegation, err := GetDelegation(stub, delegate)
+	if err != nil {
+		return err
+	}
+	if delegation == nil || delegation.Parent != walletParams.Address {
+		return model.NewCustomError(model.DelegateErrorType, delegate, "key is not delegated by signing wallet")
+	}
+	return delDelegation(stub, delegation.Parent, delegate)
+}
+
+// revokeDelegates 는 parent 지갑이 등록한 위임 키를 모두 삭제한다.
+// 서명 키가 바뀌면 이전 키로 등록한 위임도 더 이상 유효하지 않으므로 키 교체와 복구에서 사용한다.
+func revokeDelegates(stub shim.ChaincodeStubInterface, parent string) error {
+	iter, err := stub.GetStateByPartialCompositeKey(delegateParentKeyType, []string{parent})
+	if err != nil {
+		return model.NewCustomError(model.GetStateErrorType, delegateParentKeyType, err.Error())
+	}
+	defer iter.Close()
+
+	delegates := []string{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return model.NewCustomError(model.GetStateErrorType, delegateParentKeyType, err.Error())
+		}
+		_, attrs, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil || len(attrs) != 2 {
+			return model.NewCustomError(model.CreateCompositeKeyErrorType, kv.Key, "invalid delegate index key")
+		}
+		delegates = append(delegates, attrs[1])
+	}
+	for _, delegate := range delegates {
+		if err := delDelegation(stub, parent, delegate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SpendDelegate 는 위임 키로 보내는 토큰 양을 사용량에 더한다. 위임 키 서명이 아니면 아무것도 하지 않는다.
+func SpendDelegate(stub shim.ChaincodeStubInterface, walletParams *WalletParams, amount uint64) error {
+	delegation := walletParams.Delegation
+	if delegation == nil {
+		return nil
+	}
+	if delegation.Spent+amount < delegation.Spent || delegation.Spent+amount > delegation.SpendLimit {
+		return model.NewCustomError(model.DelegateErrorType, delegation.Delegate, "amount exceeds delegate spend limit")
+	}
+	delegation.Spent += amount
+	return putDelegation(stub, delegation)
+}
+
+// RequireOwnKey 는 위임 키가 아닌 지갑 자신의 키로 서명했는지 확인한다.
+// 키 관리나 위임 등록처럼 위임할 수 없는 작업에서 사용한다.
+func RequireOwnKey(walletParams *WalletParams) error {
+	if walletParams.Delegation != nil {
+		return model.NewCustomError(model.PermissionErrorType, walletParams.KeyAddress, "delegate keys cannot perform this operation")
+	}
+	return nil
+}
+
+// resolveDelegate 는 서명 키가 위임 키이면 지갑주소를 위임한 지갑으로 바꾸고 true 를 반환한다.
+// 만료되었거나 허용되지 않은 함수이면 오류를 반환한다. 만료는 클라이언트가 정한 now 기준이다.
+func resolveDelegate(stub shim.ChaincodeStubInterface, walletParams *WalletParams, function string, now int64) (bool, error) {
+	keyAddr := walletParams.Address
+	delegation, err := GetDelegation(stub, keyAddr)
+	if err != nil || delegation == nil {
+		return false, err
+	}
+	if now >= delegation.Expiry {
+		return false, model.NewCustomError(model.DelegateErrorType, keyAddr, "delegate key expired at "+strconv.FormatInt(delegation.Expiry, 10))
+	}
+	if !containsAddress(delegation.Functions, function) {
+		return false, model.NewCustomError(model.DelegateErrorType, keyAddr, "function "+function+" is not allowed for delegate key")
+	}
+
+	walletParams.Address = delegation.Parent
+	walletParams.KeyAddress = keyAddr
+	walletParams.LegacyAddress = ""
+	walletParams.Delegation = delegation
+	return true, nil
+}
+
+func delDelegation(stub shim.ChaincodeStubInterface, parent string, delegate string) error {
+	key, err := accountKey(stub, delegateKeyType, delegate)
+	if err != nil {
+		return err
+	}
+	if err := stub.DelState(key); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	indexKey, err := delegateIndexKey(stub, parent, delegate)
+	if err != nil {
+		return err
+	}
+	if err := stub.DelState(indexKey); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, indexKey, err.Error())
+	}
+	return nil
+}
+
+func putDelegateIndex(stub shim.ChaincodeStubInterface, parent string, delegate string) error {
+	key, err := delegateIndexKey(stub, parent, delegate)
+	if err != nil {
+		return err
+	}
+	// 색인은 키만 사용하지만 빈 값은 삭제로 처리되므로 1바이트를 저장한다.
+	if err := stub.PutState(key, []byte{0}); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	return nil
+}
+
+func delegateIndexKey(stub shim.ChaincodeStubInterface, parent string, delegate string) (string, error) {
+	key, err := stub.CreateCompositeKey(delegateParentKeyType, []string{parent, delegate})
+	if err != nil {
+		return "", model.NewCustomError(model.CreateCompositeKeyErrorType, delegateParentKeyType, err.Error())
+	}
+	return key, nil
+}
+
+func putDelegation(stub shim.ChaincodeStubInterface, delegation *Delegation) error {
+	key, err := accountKey(stub, delegateKeyType, delegation.Delegate)
+	if err != nil {
+		return err
+	}
+	delegationBytes, err := json.Marshal(delegation)
+	if err != nil {
+		return model.NewCustomError(model.MarshalErrorType, "Delegation", err.Error())
+	}
+	if err := stub.PutState(key, delegationBytes); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+)
+
+func TestResolveDelegate(t *testing.T) {
+	stub := shim.NewMockStub("wallet", nil)
+	parent, delegate := "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg", "uEHhePkzGgQmsAndX9MQTvviBMGNdF3YP9"
+	const now = 1700000000
+
+	stub.MockTransactionStart("delegate")
+	defer stub.MockTransactionEnd("delegate")
+	owner := &WalletParams{Address: parent, KeyAddress: parent, Sigver: SigVerSHA256}
+	if _, err := RegisterDelegate(stub, owner, DelegateConfig{Delegate: delegate, Expiry: now + 60, Functions: []string{"transfer"}, SpendLimit: 10}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		function string
+		now      int64
+		errType  string
+	}{
+		{"transfer", now + 59, ""},
+		{"transfer", now + 60, model.DelegateErrorType},
+		{"approve", now, model.DelegateErrorType},
+	}
+	for _, c := range cases {
+		wp := &WalletParams{Address: delegate, LegacyAddress: "legacy"}
+		delegated, err := resolveDelegate(stub, wp, c.function, c.now)
+		if c.errType != "" {
+			if customErr := model.ToCustomError(err); customErr == nil || customErr.ErrorType != c.errType {
+				t.Errorf("%s at %d: %v", c.function, c.now, err)
+			}
+			continue
+		}
+		if err != nil || !delegated || wp.Address != parent || wp.KeyAddress != delegate || wp.LegacyAddress != "" {
+			t.Errorf("%s at %d resolved to %+v, err %v", c.function, c.now, wp, err)
+		}
+		if err := RequireOwnKey(wp); err == nil {
+			t.Error("delegate passed RequireOwnKey")
+		}
+		if _, err := RotateKey(stub, wp, "uUjYR1DtbzXbD6cWN4qgrwTwnd7ShTgGZ1"); err == nil {
+			t.Error("delegate rotated parent key")
+		}
+	}
+
+	if err := checkNewKey(stub, delegate); err == nil {
+		t.Error("delegate key accepted as account key")
+	}
+}
+
+func TestRotateKeyRevokesDelegates(t *testing.T) {
+	stub := shim.NewMockStub("wallet", nil)
+	parent, d1, d2, newKey := "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg", "uEHhePkzGgQmsAndX9MQTvviBMGNdF3YP9", "uUjYR1DtbzXbD6cWN4qgrwTwnd7ShTgGZ1", "tcccaeH7kVa9qZQANJt7fC7gxNj3ARTPKk"
+	const now = 1700000000
+
+	stub.MockTransactionStart("rotate")
+	defer stub.MockTransactionEnd("rotate")
+	owner := &WalletParams{Address: parent, KeyAddress: parent, Sigver: SigVerSHA256}
+	for _, delegate := range []string{d1, d2} {
+		if _, err := RegisterDelegate(stub, owner, DelegateConfig{Delegate: delegate, Expiry: now + 60, Functions: []string{"transfer"}, SpendLimit: 10}, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := RotateKey(stub, owner, newKey); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, delegate := range []string{d1, d2} {
+		wp := &WalletParams{Address: delegate}
+		if delegated, err := resolveDelegate(stub, wp, "transfer", now); err != nil || delegated {
+			t.Errorf("delegate %s after rotation: delegated %v, err %v", delegate, delegated, err)
+		}
+	}
+	iter, err := stub.GetStateByPartialCompositeKey(delegateParentKeyType, []string{parent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iter.Close()
+	if iter.HasNext() {
+		t.Error("delegate index left after rotation")
+	}
+}
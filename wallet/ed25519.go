@@ -0,0 +1,47 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+
+	"github.com/jinsan74/Erc20/model"
+)
+
+// 키 종류 (WalletMeta.Keytype), 비어 있으면 KeyTypeECDSA
+const (
+	KeyTypeECDSA   = "ecdsa"
+	KeyTypeEd25519 = "ed25519"
+)
+
+// AddressVersionEd25519 는 Ed25519 키 지갑주소의 버전 바이트
+const AddressVersionEd25519 byte = 0x82
+
+// verifyEd25519Wallet 은 32바이트 hex 공개키의 Ed25519 서명을 확인하고 지갑주소를 반환한다.
+// Ed25519 는 메시지를 직접 서명하므로 canonical 메시지 원문을 그대로 검증한다.
+func verifyEd25519Wallet(walletMeta WalletMeta, orgSigMsg string) (string, error) {
+
+	if isLegacySigver(walletMeta.Sigver) {
+		return "", model.NewCustomError(model.SignatureErrorType, model.SigReasonSigver, "keytype "+KeyTypeEd25519+" requires sigver "+SigVerCanonical)
+	}
+	if walletMeta.Curve != "" {
+		return "", model.NewCustomError(model.PublicKeyErrorType, "curve", "curve must be empty for keytype "+KeyTypeEd25519)
+	}
+
+	pubBytes, err := parseEd25519PublicKey(walletMeta.Publickey)
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := hex.DecodeString(walletMeta.Sigmsg)
+	if err != nil {
+		return "", model.NewCustomError(model.HexDecodeErrorType, "sigmsg", err.Error())
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return "", model.NewCustomError(model.SignatureErrorType, model.SigReasonLength, "ed25519 signature must be 64 bytes")
+	}
+	if !ed25519.Verify(pubBytes, []byte(orgSigMsg), sig) {
+		return "", model.NewCustomError(model.SignatureErrorType, model.SigReasonMismatch, "Signature is Fail")
+	}
+
+	return versionedAddress(AddressVersionEd25519, pubBytes), nil
+}
@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+)
+
+// 기존 형식 주소 매핑 키 (legacy -> 새 주소, 새 주소 -> legacy)
+const (
+	legacyAddressKeyType = "legacyAddress"
+	linkedLegacyKeyType  = "linkedLegacy"
+)
+
+// ResolveAddress 는 새 주소로 매핑된 기존 형식 주소이면 새 주소를, 아니면 address 를 그대로 반환한다.
+func ResolveAddress(stub shim.ChaincodeStubInterface, address string) (string, error) {
+	mapped, err := getAddressLink(stub, legacyAddressKeyType, address)
+	if err != nil {
+		return "", err
+	}
+	if mapped == "" {
+		return address, nil
+	}
+	return mapped, nil
+}
+
+// LinkLegacyAddress 는 서명자의 기존 형식 주소를 새 주소로 매핑한다.
+// 이미 같은 주소로 매핑되어 있으면 false 를 반환한다. 잔액 이전은 호출하는 쪽에서 한다.
+func LinkLegacyAddress(stub shim.ChaincodeStubInterface, walletParams *WalletParams) (bool, error) {
+	legacy, address := walletParams.LegacyAddress, walletParams.Address
+	if legacy == "" {
+		return false, model.NewCustomError(model.PublicKeyErrorType, address, "publickey has no legacy address")
+	}
+
+	mapped, err := getAddressLink(stub, legacyAddressKeyType, legacy)
+	if err != nil {
+		return false, err
+	}
+	if mapped == address {
+		return false, nil
+	}
+	if mapped != "" {
+		return false, model.NewCustomError(model.PublicKeyErrorType, legacy, "legacy address is mapped to another address")
+	}
+
+	if err := putAddressLink(stub, legacyAddressKeyType, legacy, address); err != nil {
+		return false, err
+	}
+	if err := putAddressLink(stub, linkedLegacyKeyType, address, legacy); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func getAddressLink(stub shim.ChaincodeStubInterface, keyType string, address string) (string, error) {
+	key, err := addressLinkKey(stub, keyType, address)
+	if err != nil {
+		return "", err
+	}
+	value, err := stub.GetState(key)
+	if err != nil {
+		return "", model.NewCustomError(model.GetStateErrorType, key, err.Error())
+	}
+	return string(value), nil
+}
+
+func putAddressLink(stub shim.ChaincodeStubInterface, keyType string, address string, linked string) error {
+	key, err := addressLinkKey(stub, keyType, address)
+	if err != nil {
+		return err
+	}
+	if err := stub.PutState(key, []byte(linked)); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	return nil
+}
+
+func addressLinkKey(stub shim.ChaincodeStubInterface, keyType string, address string) (string, error) {
+	key, err := stub.CreateCompositeKey(keyType, []string{address})
+	if err != nil {
+		return "", model.NewCustomError(model.CreateCompositeKeyErrorType, keyType, err.Error())
+	}
+	return key, nil
+}
@@ -0,0 +1,178 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+)
+
+// KeyTypeMultisig 는 multisig 봉투의 키 종류. Publickey 에 multisig 지갑주소를 넣는다.
+const KeyTypeMultisig = "multisig"
+
+// AddressVersionMultisig 는 multisig 지갑주소의 버전 바이트
+const AddressVersionMultisig byte = 0x83
+
+// MaxMultisigMembers 는 multisig 멤버 수 상한
+const MaxMultisigMembers = 16
+
+const multisigKeyType = "multisig"
+
+// MultisigPolicy is multisig 지갑 정책
+type MultisigPolicy struct {
+	Threshold int      `json:"threshold"`
+	Members   []string `json:"members"` // 멤버 지갑주소
+}
+
+// SignerSig is multisig 봉투의 멤버 서명. 멤버는 모두 같은 canonical 메시지에 서명한다.
+type SignerSig struct {
+	Publickey string `json:"publickey"`
+	Sigmsg    string `json:"sigmsg"`
+	Curve     string `json:"curve,omitempty"`
+	Keytype   string `json:"keytype,omitempty"`
+}
+
+// MultisigAddress 는 정책으로 multisig 지갑주소를 만든다.
+// 멤버 순서와 관계없이 같은 정책이면 같은 주소가 된다. 정규화된 정책도 함께 반환한다.
+func MultisigAddress(policy MultisigPolicy) (string, MultisigPolicy, error) {
+	normalized := MultisigPolicy{Threshold: policy.Threshold}
+
+	if len(policy.Members) == 0 || len(policy.Members) > MaxMultisigMembers {
+		return "", normalized, model.NewCustomError(model.MandatoryPrameterErrorType, "members", "multisig needs 1 to "+strconv.Itoa(MaxMultisigMembers)+" members")
+	}
+	if policy.Threshold < 1 || policy.Threshold > len(policy.Members) {
+		return "", normalized, model.NewCustomError(model.MandatoryPrameterErrorType, "threshold", "threshold must be between 1 and number of members")
+	}
+
+	// 주소 문자열 대신 디코딩한 버전+해시로 정렬해서 표기와 관계없이 같은 순서가 되게 한다.
+	decoded := make([][]byte, 0, len(policy.Members))
+	for _, member := range policy.Members {
+		version, hash, err := DecodeAddress(member)
+		if err != nil {
+			return "", normalized, err
+		}
+		if version == AddressVersionMultisig {
+			return "", normalized, model.NewCustomError(model.AddressErrorType, member, "multisig wallet can not be a multisig member")
+		}
+		decoded = append(decoded, append([]byte{version}, hash...))
+	}
+	sort.Slice(decoded, func(i, j int) bool { return bytes.Compare(decoded[i], decoded[j]) < 0 })
+
+	policyBytes := []byte{byte(policy.Threshold), byte(len(decoded))}
+	for i, member := range decoded {
+		if i > 0 && bytes.Equal(member, decoded[i-1]) {
+			return "", normalized, model.NewCustomError(model.AddressErrorType, base58CheckEncode(member[0], member[1:]), "duplicate multisig member")
+		}
+		policyBytes = append(policyBytes, member...)
+		normalized.Members = append(normalized.Members, base58CheckEncode(member[0], member[1:]))
+	}
+	return versionedAddress(AddressVersionMultisig, policyBytes), normalized, nil
+}
+
+// RegisterMultisig 는 정책을 원장에 등록하고 multisig 지갑주소를 반환한다.
+// 주소가 정책으로 정해지므로 같은 정책을 다시 등록해도 결과는 같다.
+func RegisterMultisig(stub shim.ChaincodeStubInterface, policy MultisigPolicy) (string, error) {
+	address, normalized, err := MultisigAddress(policy)
+	if err != nil {
+		return "", err
+	}
+
+	policyBytes, err := json.Marshal(normalized)
+	if err != nil {
+		return "", model.NewCustomError(model.MarshalErrorType, "MultisigPolicy", err.Error())
+	}
+	key, err := multisigKey(stub, address)
+	if err != nil {
+		return "", err
+	}
+	if err := stub.PutState(key, policyBytes); err != nil {
+		return "", model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	return address, nil
+}
+
+// GetMultisig 는 등록된 multisig 정책을 반환한다. 등록되지 않았으면 nil 을 반환한다.
+func GetMultisig(stub shim.ChaincodeStubInterface, address string) (*MultisigPolicy, error) {
+	key, err := multisigKey(stub, address)
+	if err != nil {
+		return nil, err
+	}
+	policyBytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, model.NewCustomError(model.GetStateErrorType, key, err.Error())
+	}
+	if policyBytes == nil {
+		return nil, nil
+	}
+	policy := MultisigPolicy{}
+	if err := json.Unmarshal(policyBytes, &policy); err != nil {
+		return nil, model.NewCustomError(model.UnMarshalErrorType, key, err.Error())
+	}
+	return &policy, nil
+}
+
+func multisigKey(stub shim.ChaincodeStubInterface, address string) (string, error) {
+	key, err := stub.CreateCompositeKey(multisigKeyType, []string{address})
+	if err != nil {
+		return "", model.NewCustomError(model.CreateCompositeKeyErrorType, multisigKeyType, err.Error())
+	}
+	return key, nil
+}
+
+// verifyMultisigWallet 은 멤버 서명이 threshold 이상인지 확인하고 multisig 지갑주소와 서명한 멤버 주소를 반환한다.
+func verifyMultisigWallet(walletMeta WalletMeta, orgSigMsg string, sigCfg model.SigConfig, policy *MultisigPolicy) (string, []string, error) {
+
+	if isLegacySigver(walletMeta.Sigver) {
+		return "", nil, model.NewCustomError(model.SignatureErrorType, model.SigReasonSigver, "keytype "+KeyTypeMultisig+" requires sigver "+SigVerCanonical)
+	}
+	if walletMeta.Curve != "" || walletMeta.Sigmsg != "" {
+		return "", nil, model.NewCustomError(model.PublicKeyErrorType, "publickey", "multisig envelope carries signatures in sigs only")
+	}
+	if policy == nil {
+		return "", nil, model.NewCustomError(model.PublicKeyErrorType, walletMeta.Publickey, "multisig wallet is not registered")
+	}
+	address, _, err := MultisigAddress(*policy)
+	if err != nil {
+		return "", nil, err
+	}
+	if address != walletMeta.Publickey {
+		return "", nil, model.NewCustomError(model.PublicKeyErrorType, walletMeta.Publickey, "multisig policy does not match address")
+	}
+
+	members := make(map[string]bool, len(policy.Members))
+	for _, member := range policy.Members {
+		members[member] = false
+	}
+
+	signers := make([]string, 0, len(walletMeta.Sigs))
+	for _, signerSig := range walletMeta.Sigs {
+		signerMeta := WalletMeta{
+			Publickey: signerSig.Publickey,
+			Sigmsg:    signerSig.Sigmsg,
+			Curve:     signerSig.Curve,
+			Keytype:   signerSig.Keytype,
+			Sigver:    walletMeta.Sigver,
+		}
+		signer, _, err := verifySigner(signerMeta, orgSigMsg, sigCfg)
+		if err != nil {
+			return "", nil, err
+		}
+		signed, ok := members[signer]
+		if !ok {
+			return "", nil, model.NewCustomError(model.SignatureErrorType, model.SigReasonMember, signer+" is not a multisig member")
+		}
+		if signed {
+			return "", nil, model.NewCustomError(model.SignatureErrorType, model.SigReasonDuplicate, signer+" signed more than once")
+		}
+		members[signer] = true
+		signers = append(signers, signer)
+	}
+
+	if len(signers) < policy.Threshold {
+		return "", nil, model.NewCustomError(model.SignatureErrorType, model.SigReasonThreshold, strconv.Itoa(len(signers))+" of "+strconv.Itoa(policy.Threshold)+" required signatures")
+	}
+	return address, signers, nil
+}
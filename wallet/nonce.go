@@ -0,0 +1,107 @@
+package wallet
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+)
+
+const nonceKeyType = "walletNonce"
+
+// GetNonce 는 address 가 다음에 서명해야 할 nonce 를 반환한다.
+// 기존 형식 주소와 매핑된 주소이면 두 주소 중 큰 nonce 를 기준으로 한다.
+func GetNonce(stub shim.ChaincodeStubInterface, address string) (uint64, error) {
+	address, err := ResolveAddress(stub, address)
+	if err != nil {
+		return 0, err
+	}
+	legacy, err := getAddressLink(stub, linkedLegacyKeyType, address)
+	if err != nil {
+		return 0, err
+	}
+	last, err := lastNonceOf(stub, address, legacy)
+	if err != nil {
+		return 0, err
+	}
+	if last == math.MaxUint64 {
+		return 0, model.NewCustomError(model.NonceErrorType, address, "nonce is exhausted")
+	}
+	return last + 1, nil
+}
+
+// useNonce 는 nonce 가 마지막으로 사용된 값의 바로 다음 값일 때만 기록한다.
+// 건너뛴 nonce 를 받으면 큰 값 하나로 지갑의 nonce 를 소진시킬 수 있으므로 순서대로만 받는다.
+// legacy 가 있으면 기존 형식 주소에서 쓰던 nonce 도 확인한다.
+func useNonce(stub shim.ChaincodeStubInterface, address string, legacy string, nonceStr string) error {
+
+	nonce, err := strconv.ParseUint(nonceStr, 10, 64)
+	// 같은 nonce 가 여러 표기(앞의 0 등)로 서명되지 않도록 10진수 표준 표기만 받는다.
+	if err != nil || strconv.FormatUint(nonce, 10) != nonceStr {
+		return model.NewCustomError(model.NonceErrorType, address, "nonce must be unsigned integer without leading zeros")
+	}
+
+	last, err := lastNonceOf(stub, address, legacy)
+	if err != nil {
+		return err
+	}
+	if last == math.MaxUint64 {
+		return model.NewCustomError(model.NonceErrorType, address, "nonce is exhausted")
+	}
+	if nonce != last+1 {
+		return model.NewCustomError(model.NonceErrorType, address, "nonce "+nonceStr+" is not the next nonce, next nonce is "+strconv.FormatUint(last+1, 10))
+	}
+
+	key, err := nonceKey(stub, address)
+	if err != nil {
+		return err
+	}
+	if err := stub.PutState(key, []byte(nonceStr)); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, key, err.Error())
+	}
+	return nil
+}
+
+// lastNonceOf 는 address 와 기존 형식 주소 legacy 중 큰 nonce 를 반환한다.
+func lastNonceOf(stub shim.ChaincodeStubInterface, address string, legacy string) (uint64, error) {
+	last, err := lastNonce(stub, address)
+	if err != nil || legacy == "" {
+		return last, err
+	}
+	legacyLast, err := lastNonce(stub, legacy)
+	if err != nil {
+		return 0, err
+	}
+	if legacyLast > last {
+		return legacyLast, nil
+	}
+	return last, nil
+}
+
+func lastNonce(stub shim.ChaincodeStubInterface, address string) (uint64, error) {
+	key, err := nonceKey(stub, address)
+	if err != nil {
+		return 0, err
+	}
+	value, err := stub.GetState(key)
+	if err != nil {
+		return 0, model.NewCustomError(model.GetStateErrorType, key, err.Error())
+	}
+	if value == nil {
+		return 0, nil
+	}
+	last, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return 0, model.NewCustomError(model.ConvertErrorType, key, err.Error())
+	}
+	return last, nil
+}
+
+func nonceKey(stub shim.ChaincodeStubInterface, address string) (string, error) {
+	key, err := stub.CreateCompositeKey(nonceKeyType, []string{address})
+	if err != nil {
+		return "", model.NewCustomError(model.CreateCompositeKeyErrorType, nonceKeyType, err.Error())
+	}
+	return key, nil
+}
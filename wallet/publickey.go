@@ -0,0 +1,192 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"strings"
+
+	"github.com/jinsan74/Erc20/model"
+)
+
+// oidPublicKeyECDSA 는 SubjectPublicKeyInfo 의 id-ecPublicKey 알고리즘
+var oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+// subjectPublicKeyInfo is X.509 SubjectPublicKeyInfo
+// crypto/x509 는 secp256k1 을 지원하지 않으므로 직접 디코딩한다.
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// isXYPublicKey 는 기존 "X:Y" hex 쌍 형식인지 확인한다.
+func isXYPublicKey(publicKeyStr string) bool {
+	return strings.Contains(publicKeyStr, ":")
+}
+
+// parseECDSAPublicKey 는 공개키 문자열을 곡선 위의 점으로 변환한다.
+// "X:Y" hex 쌍, SEC1 압축(0x02/0x03)/비압축(0x04) hex, PEM 또는 hex DER SubjectPublicKeyInfo 를 받는다.
+// 같은 키는 형식과 관계없이 같은 점이 되므로 지갑주소도 같다.
+func parseECDSAPublicKey(publicKeyStr string, spec curveSpec) (*ecdsa.PublicKey, error) {
+	switch {
+	case isXYPublicKey(publicKeyStr):
+		return parseXYPublicKey(publicKeyStr, spec)
+	case strings.HasPrefix(publicKeyStr, "-----BEGIN"):
+		der, err := decodePublicKeyPEM(publicKeyStr)
+		if err != nil {
+			return nil, err
+		}
+		return parseSPKIPublicKey(der, spec)
+	}
+
+	raw, err := hex.DecodeString(publicKeyStr)
+	if err != nil {
+		return nil, model.NewCustomError(model.HexDecodeErrorType, "publickey", err.Error())
+	}
+	if len(raw) > 0 && raw[0] == 0x30 {
+		return parseSPKIPublicKey(raw, spec)
+	}
+	return parseSEC1PublicKey(raw, spec)
+}
+
+func parseXYPublicKey(publicKeyStr string, spec curveSpec) (*ecdsa.PublicKey, error) {
+	publicKeySlice := strings.Split(publicKeyStr, ":")
+	if len(publicKeySlice) != 2 || publicKeySlice[0] == "" || publicKeySlice[1] == "" {
+		return nil, model.NewCustomError(model.PublicKeyErrorType, "publickey", "publickey must be X:Y hex pair")
+	}
+	xBytes, err := hex.DecodeString(publicKeySlice[0])
+	if err != nil {
+		return nil, model.NewCustomError(model.HexDecodeErrorType, "publickey X", err.Error())
+	}
+	yBytes, err := hex.DecodeString(publicKeySlice[1])
+	if err != nil {
+		return nil, model.NewCustomError(model.HexDecodeErrorType, "publickey Y", err.Error())
+	}
+	return newECDSAPublicKey(new(big.Int).SetBytes(xBytes), new(big.Int).SetBytes(yBytes), spec)
+}
+
+// parseSEC1PublicKey 는 SEC1 압축(33바이트) 또는 비압축(65바이트) 공개키를 디코딩한다.
+func parseSEC1PublicKey(raw []byte, spec curveSpec) (*ecdsa.PublicKey, error) {
+	byteLen := (spec.curve.Params().BitSize + 7) / 8
+
+	switch {
+	case len(raw) == 1+byteLen && (raw[0] == 0x02 || raw[0] == 0x03):
+		x := new(big.Int).SetBytes(raw[1:])
+		y, err := decompressY(x, raw[0] == 0x03, spec)
+		if err != nil {
+			return nil, err
+		}
+		return newECDSAPublicKey(x, y, spec)
+	case len(raw) == 1+2*byteLen && raw[0] == 0x04:
+		x := new(big.Int).SetBytes(raw[1 : 1+byteLen])
+		y := new(big.Int).SetBytes(raw[1+byteLen:])
+		return newECDSAPublicKey(x, y, spec)
+	default:
+		return nil, model.NewCustomError(model.PublicKeyErrorType, "publickey", "publickey must be X:Y, SEC1 or SubjectPublicKeyInfo")
+	}
+}
+
+// decompressY 는 y² = x³ + ax + b (mod p) 에서 홀짝이 맞는 y 를 구한다.
+func decompressY(x *big.Int, odd bool, spec curveSpec) (*big.Int, error) {
+	params := spec.curve.Params()
+	p := params.P
+	if x.Cmp(p) >= 0 {
+		return nil, model.NewCustomError(model.CurvePointErrorType, "publickey", "publickey X is out of range")
+	}
+
+	y2 := new(big.Int).Exp(x, big.NewInt(3), p)
+	ax := new(big.Int).Mul(big.NewInt(spec.a), x)
+	y2.Add(y2, ax)
+	y2.Add(y2, params.B)
+	y2.Mod(y2, p)
+
+	y := new(big.Int).ModSqrt(y2, p)
+	if y == nil {
+		return nil, model.NewCustomError(model.CurvePointErrorType, "publickey", "publickey is not on curve "+params.Name)
+	}
+	if (y.Bit(0) == 1) != odd {
+		y.Sub(p, y)
+	}
+	return y, nil
+}
+
+// parseSPKIPublicKey 는 DER SubjectPublicKeyInfo 를 디코딩한다. namedCurve 는 spec 의 곡선이어야 한다.
+func parseSPKIPublicKey(der []byte, spec curveSpec) (*ecdsa.PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	rest, err := asn1.Unmarshal(der, &spki)
+	if err != nil {
+		return nil, model.NewCustomError(model.PublicKeyErrorType, "publickey", err.Error())
+	}
+	if len(rest) > 0 {
+		return nil, model.NewCustomError(model.PublicKeyErrorType, "publickey", "trailing data after SubjectPublicKeyInfo")
+	}
+	if !spki.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		return nil, model.NewCustomError(model.PublicKeyErrorType, "publickey", "SubjectPublicKeyInfo is not an EC public key")
+	}
+
+	var namedCurve asn1.ObjectIdentifier
+	if rest, err := asn1.Unmarshal(spki.Algorithm.Parameters.FullBytes, &namedCurve); err != nil || len(rest) > 0 {
+		return nil, model.NewCustomError(model.PublicKeyErrorType, "publickey", "SubjectPublicKeyInfo has no named curve")
+	}
+	if !namedCurve.Equal(spec.oid) {
+		return nil, model.NewCustomError(model.PublicKeyErrorType, "curve", "SubjectPublicKeyInfo curve does not match curve "+spec.curve.Params().Name)
+	}
+	if spki.PublicKey.BitLength%8 != 0 {
+		return nil, model.NewCustomError(model.PublicKeyErrorType, "publickey", "SubjectPublicKeyInfo key is not byte aligned")
+	}
+	return parseSEC1PublicKey(spki.PublicKey.Bytes, spec)
+}
+
+// decodePublicKeyPEM 은 PUBLIC KEY PEM 블록 하나를 DER 로 디코딩한다.
+func decodePublicKeyPEM(publicKeyStr string) ([]byte, error) {
+	block, rest := pem.Decode([]byte(publicKeyStr))
+	if block == nil || block.Type != "PUBLIC KEY" || len(bytes.TrimSpace(rest)) > 0 {
+		return nil, model.NewCustomError(model.PublicKeyErrorType, "publickey", "publickey must be a single PUBLIC KEY PEM block")
+	}
+	return block.Bytes, nil
+}
+
+// newECDSAPublicKey 는 좌표가 [0, p) 범위의 곡선 위 점인지 확인한다.
+func newECDSAPublicKey(x, y *big.Int, spec curveSpec) (*ecdsa.PublicKey, error) {
+	params := spec.curve.Params()
+	if x.Cmp(params.P) >= 0 || y.Cmp(params.P) >= 0 || !spec.curve.IsOnCurve(x, y) {
+		return nil, model.NewCustomError(model.CurvePointErrorType, "publickey", "publickey is not on curve "+params.Name)
+	}
+	return &ecdsa.PublicKey{Curve: spec.curve, X: x, Y: y}, nil
+}
+
+// parseEd25519PublicKey 는 32바이트 hex 공개키, PEM 또는 hex DER SubjectPublicKeyInfo 를 디코딩한다.
+func parseEd25519PublicKey(publicKeyStr string) (ed25519.PublicKey, error) {
+	var der []byte
+	if strings.HasPrefix(publicKeyStr, "-----BEGIN") {
+		var err error
+		if der, err = decodePublicKeyPEM(publicKeyStr); err != nil {
+			return nil, err
+		}
+	} else {
+		pubBytes, err := hex.DecodeString(publicKeyStr)
+		if err != nil {
+			return nil, model.NewCustomError(model.HexDecodeErrorType, "publickey", err.Error())
+		}
+		if len(pubBytes) == ed25519.PublicKeySize {
+			return ed25519.PublicKey(pubBytes), nil
+		}
+		der = pubBytes
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, model.NewCustomError(model.PublicKeyErrorType, "publickey", "ed25519 publickey must be 32 bytes or SubjectPublicKeyInfo")
+	}
+	edPub, ok := pub.(ed25519.PublicKey)
+	if !ok {
+		return nil, model.NewCustomError(model.PublicKeyErrorType, "publickey", "SubjectPublicKeyInfo is not an ed25519 public key")
+	}
+	return edPub, nil
+}
@@ -0,0 +1,39 @@
+package wallet
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+)
+
+const sigConfigKey = "WALLET_SIG_CONFIG"
+
+// GetSigConfig 는 원장의 서명 방식 설정을 반환한다. 설정이 없으면 모든 방식을 허용한다.
+func GetSigConfig(stub shim.ChaincodeStubInterface) (model.SigConfig, error) {
+	cfg := model.SigConfig{}
+
+	cfgBytes, err := stub.GetState(sigConfigKey)
+	if err != nil {
+		return cfg, model.NewCustomError(model.GetStateErrorType, sigConfigKey, err.Error())
+	}
+	if cfgBytes == nil {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(cfgBytes, &cfg); err != nil {
+		return cfg, model.NewCustomError(model.UnMarshalErrorType, sigConfigKey, err.Error())
+	}
+	return cfg, nil
+}
+
+// PutSigConfig 는 서명 방식 설정을 저장한다. 권한 확인은 호출하는 쪽에서 한다.
+func PutSigConfig(stub shim.ChaincodeStubInterface, cfg model.SigConfig) error {
+	cfgBytes, err := json.Marshal(cfg)
+	if err != nil {
+		return model.NewCustomError(model.MarshalErrorType, "SigConfig", err.Error())
+	}
+	if err := stub.PutState(sigConfigKey, cfgBytes); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, sigConfigKey, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package wallet
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/jinsan74/Erc20/model"
+)
+
+const txTimeConfigKey = "WALLET_TXTIME_CONFIG"
+
+// DefaultTxTimeConfig 는 설정이 없을 때 사용하는 ±10초 범위
+var DefaultTxTimeConfig = model.TxTimeConfig{PastSec: 10, FutureSec: 10}
+
+// GetTxTimeConfig 는 원장의 txtime 허용 범위를 반환한다. 설정이 없으면 DefaultTxTimeConfig.
+func GetTxTimeConfig(stub shim.ChaincodeStubInterface) (model.TxTimeConfig, error) {
+	cfg := DefaultTxTimeConfig
+
+	cfgBytes, err := stub.GetState(txTimeConfigKey)
+	if err != nil {
+		return cfg, model.NewCustomError(model.GetStateErrorType, txTimeConfigKey, err.Error())
+	}
+	if cfgBytes == nil {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(cfgBytes, &cfg); err != nil {
+		return cfg, model.NewCustomError(model.UnMarshalErrorType, txTimeConfigKey, err.Error())
+	}
+	return cfg, nil
+}
+
+// PutTxTimeConfig 는 txtime 허용 범위를 저장한다. 권한 확인은 호출하는 쪽에서 한다.
+func PutTxTimeConfig(stub shim.ChaincodeStubInterface, cfg model.TxTimeConfig) error {
+	if cfg.PastSec < 0 || cfg.FutureSec < 0 {
+		return model.NewCustomError(model.TxTimeStampErrorType, "TxTimeConfig", "pastsec and futuresec must be positive")
+	}
+
+	cfgBytes, err := json.Marshal(cfg)
+	if err != nil {
+		return model.NewCustomError(model.MarshalErrorType, "TxTimeConfig", err.Error())
+	}
+	if err := stub.PutState(txTimeConfigKey, cfgBytes); err != nil {
+		return model.NewCustomError(model.PutStateErrorType, txTimeConfigKey, err.Error())
+	}
+	return nil
+}
+
+// checkTxTime 은 txtime 이 nowTime 기준 허용 범위 안인지 확인한다.
+// nowTime 은 stub.GetTxTimestamp 의 proposal timestamp 로, 블록 시간이 아니라 제출한 클라이언트가 정한 값이다.
+// 그래서 이 검사는 봉투 서명 시각과 proposal 시각의 차이(클라이언트 시계 차이)를 제한할 뿐
+// 원장 시간 기준의 만료를 보장하지 않는다. 재사용 방지는 nonce 로 한다.
+func checkTxTime(txTime string, nowTime int64, cfg model.TxTimeConfig) error {
+	if cfg.Disabled {
+		return nil
+	}
+
+	txTimeStamp, err := strconv.ParseInt(txTime, 10, 64)
+	if err != nil {
+		return model.NewCustomError(model.TxTimeStampErrorType, "txtime", "txtime must be unix seconds")
+	}
+
+	betweenSec := nowTime - txTimeStamp
+	if betweenSec > cfg.PastSec {
+		return model.NewCustomError(model.TxTimeStampErrorType, "txtime", "tx time is older than "+strconv.FormatInt(cfg.PastSec, 10)+" sec")
+	}
+	if -betweenSec > cfg.FutureSec {
+		return model.NewCustomError(model.TxTimeStampErrorType, "txtime", "tx time is more than "+strconv.FormatInt(cfg.FutureSec, 10)+" sec in the future")
+	}
+	return nil
+}
@@ -5,35 +5,33 @@
 package wallet
 
 import (
+	"bytes"
 	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/asn1"
 	"encoding/hex"
 	"fmt"
 	"math/big"
-	"strconv"
 	"strings"
 
 	"github.com/jinsan74/Erc20/model"
-	"golang.org/x/crypto/ripemd160"
 )
 
 /*
  * 트랜잭션 체크 함수 : 지갑주소와 실제 트랜잭션 파라미터를 리턴한다.
+ * multisig 봉투이면 policy 는 Publickey 의 등록된 정책이다. (등록되지 않았으면 nil)
  */
-func vaildWallet(walletMeta WalletMeta) ([]string, error) {
+func vaildWallet(walletMeta WalletMeta, sigCtx SigContext, txTimeCfg model.TxTimeConfig, sigCfg model.SigConfig, policy *MultisigPolicy) (*WalletParams, error) {
 
 	var err error
 	//--필수 파라미터 체크------------
-	if walletMeta.Publickey == "" || walletMeta.Sigmsg == "" || walletMeta.Txtime == "" {
+	if walletMeta.Publickey == "" || (walletMeta.Sigmsg == "" && len(walletMeta.Sigs) == 0) || walletMeta.Txtime == "" {
 		return nil, model.NewCustomError(model.MandatoryPrameterErrorType, "Wallet Parameter", "incorrect number of transaction parameter")
 	}
 
 	publicKeyStr := walletMeta.Publickey
 	transData := walletMeta.Transdata
-	sigData := walletMeta.Sigmsg
 	nowTimeStamp := walletMeta.Nowtime
 	txTime := walletMeta.Txtime
 	transJdata := ""
@@ -42,104 +40,143 @@ func vaildWallet(walletMeta WalletMeta) ([]string, error) {
 		transJdata = walletMeta.Transjdata
 	}
 
-	//nowTimeStamp, _ := strconv.ParseInt(nowTime, 10, 64)
-	txTimeStamp, _ := strconv.ParseInt(txTime, 10, 64)
-
-	betweenSec := nowTimeStamp - txTimeStamp
-	fmt.Println("BETWEEN SEC:", betweenSec)
-
-	if betweenSec > 10 || betweenSec < -10 {
-		//return nil, model.NewCustomError(model.TxTimeStampErrorType, "", "Must Be Tx Time inner +-10 Sec")
+	//--TX TIME 체크-------------
+	if err := checkTxTime(txTime, nowTimeStamp, txTimeCfg); err != nil {
+		return nil, err
 	}
 
-	//--Public Key 생성----------
-	publicKeySlice := strings.Split(publicKeyStr, ":")
-	xHexStr := publicKeySlice[0]
-	yHexStr := publicKeySlice[1]
-	ePubKey := hexToPublicKey(xHexStr, yHexStr)
-
 	//--ORG SIG MSG--------------------
-	orgSigMsg := publicKeyStr + txTime
-
-	//--ORG SIG MSG Hash 처리---------------------------
-	orgSigMsgByte := []byte(orgSigMsg)
-	orgSigMsgHash := sha256.Sum256(orgSigMsgByte)
-	sigMsg := fmt.Sprintf("%x", orgSigMsgHash)
-	//--SIGNATURE 비교-----------------
-	sigHex, _ := hex.DecodeString(sigData)
-	sigok := verifyMySig(ePubKey, sigMsg, sigHex)
-	fmt.Println("SIG OK:", sigok)
+	orgSigMsg, err := signedMessage(walletMeta, sigCtx)
+	if err != nil {
+		return nil, err
+	}
 
-	if !sigok {
-		return nil, model.NewCustomError(model.SignatureErrorType, "", "Signature is Fail")
+	//--서명 검증 및 지갑주소 생성---------
+	var walletAddr, legacyAddr string
+	var signers []string
+	if walletMeta.Keytype == KeyTypeMultisig {
+		walletAddr, signers, err = verifyMultisigWallet(walletMeta, orgSigMsg, sigCfg, policy)
+	} else if len(walletMeta.Sigs) > 0 {
+		err = model.NewCustomError(model.MandatoryPrameterErrorType, "sigs", "sigs requires keytype "+KeyTypeMultisig)
+	} else {
+		walletAddr, legacyAddr, err = verifySigner(walletMeta, orgSigMsg, sigCfg)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	//--Double Hash 처리---------------------------
-	publicKeyByte1 := []byte(publicKeyStr)
-	publicKeyHash1 := sha256.Sum256(publicKeyByte1)
+	fmt.Println("Wallet Address: ", walletAddr)
 
-	publicKeyStr2 := fmt.Sprintf("%x", publicKeyHash1)
-	publicKeyByte2 := []byte(publicKeyStr2)
-	publicKeyHash2 := sha256.Sum256(publicKeyByte2)
+	walletParams := &WalletParams{
+		Address:       walletAddr,
+		LegacyAddress: legacyAddr,
+		Publickey:     publicKeyStr,
+		Signers:       signers,
+		Sigver:        walletMeta.Sigver,
+		Jdata:         transJdata,
+	}
+	if len(transData) > 0 {
+		walletParams.Params = strings.Split(transData, ",")
+	}
 
-	//--Public Key SAH256 처리----
-	shaPubKey := fmt.Sprintf("%x", publicKeyHash2)
+	return walletParams, nil
+}
 
-	//--RIPEMD160 로직 추가---
-	h := ripemd160.New()
-	h.Write([]byte(shaPubKey))
-	shaPubKey = fmt.Sprintf("%x", h.Sum(nil))
-	//----------------------
+// verifySigner 는 키 종류별로 단일 서명을 검증하고 지갑주소와 기존 형식 주소를 반환한다.
+func verifySigner(walletMeta WalletMeta, orgSigMsg string, sigCfg model.SigConfig) (string, string, error) {
+	switch walletMeta.Keytype {
+	case "", KeyTypeECDSA:
+		// SHA-1 digest 는 클라이언트 마이그레이션 후 설정으로 막을 수 있다.
+		if sigCfg.DisableSHA1 && walletMeta.Sigver != SigVerSHA256 {
+			return "", "", model.NewCustomError(model.SignatureErrorType, model.SigReasonSigver, "SHA-1 signature digest is disabled, use sigver "+SigVerSHA256)
+		}
+		return verifyECDSAWallet(walletMeta, orgSigMsg)
+	case KeyTypeEd25519:
+		walletAddr, err := verifyEd25519Wallet(walletMeta, orgSigMsg)
+		return walletAddr, "", err
+	default:
+		return "", "", model.NewCustomError(model.PublicKeyErrorType, "keytype", "unsupported keytype "+walletMeta.Keytype)
+	}
+}
 
-	//--Public Key SAH256 => BASE58Check 처리 ----
-	shaPubkeyStr := new(big.Int)
-	shaPubkeyStr.SetString(shaPubKey, 32)
+// verifyECDSAWallet 은 "X:Y" 공개키의 ECDSA 서명을 확인하고 지갑주소를 반환한다.
+// 기존 형식 주소가 있는 곡선이면 legacy 주소도 함께 반환한다.
+func verifyECDSAWallet(walletMeta WalletMeta, orgSigMsg string) (string, string, error) {
 
-	shaPubkeyDigit := fmt.Sprint(shaPubkeyStr)
+	publicKeyStr := walletMeta.Publickey
+	sigData := walletMeta.Sigmsg
 
-	walletAddr, err := convertToBase58(shaPubkeyDigit, 10)
+	//--곡선 확인----------
+	spec, err := lookupCurve(walletMeta.Curve)
 	if err != nil {
-		return nil, model.NewCustomError(model.SignatureErrorType, "", "Signature is Fail")
+		return "", "", err
+	}
+	if !spec.legacySig && isLegacySigver(walletMeta.Sigver) {
+		return "", "", model.NewCustomError(model.SignatureErrorType, model.SigReasonSigver, "curve "+walletMeta.Curve+" requires sigver "+SigVerCanonical)
 	}
 
-	fmt.Println("Wallet Address: ", walletAddr)
-
-	retParams := make([]string, 1)
-
-	if len(transJdata) > 0 {
-		retParams = make([]string, 2)
-		retParams[0] = walletAddr
-		retParams[1] = transJdata
-		return retParams, nil
+	//--Public Key 생성----------
+	ePubKey, err := parseECDSAPublicKey(publicKeyStr, spec)
+	if err != nil {
+		return "", "", err
 	}
 
-	if len(transData) > 0 {
-		returnMsg := walletAddr
-		returnMsg = walletAddr + "," + transData
-		retParams = strings.Split(returnMsg, ",")
-	} else {
-		retParams[0] = walletAddr
+	//--ORG SIG MSG Hash 처리---------------------------
+	digest := signatureDigest(walletMeta.Sigver, orgSigMsg)
+	//--SIGNATURE 비교-----------------
+	sigHex, err := hex.DecodeString(sigData)
+	if err != nil {
+		return "", "", model.NewCustomError(model.HexDecodeErrorType, "sigmsg", err.Error())
+	}
+	// 기존 방식은 high-S 를 정규화해서 받고, canonical 방식부터는 low-S 만 받는다.
+	if err := verifyMySig(ePubKey, digest, sigHex, !isLegacySigver(walletMeta.Sigver)); err != nil {
+		return "", "", err
 	}
 
-	return retParams, nil
+	//--지갑주소 생성---------------------------
+	walletAddr := walletAddress(ePubKey, spec)
+	// 기존 형식 주소는 "X:Y" 문자열로만 만들어졌다.
+	if !spec.legacyAddr || !isXYPublicKey(publicKeyStr) {
+		return walletAddr, "", nil
+	}
+	legacyAddr, err := legacyAddress(publicKeyStr)
+	if err != nil {
+		return "", "", err
+	}
+	return walletAddr, legacyAddr, nil
 }
 
-func hexToPublicKey(xHex string, yHex string) *ecdsa.PublicKey {
-	xBytes, _ := hex.DecodeString(xHex)
-	x := new(big.Int)
-	x.SetBytes(xBytes)
-
-	yBytes, _ := hex.DecodeString(yHex)
-	y := new(big.Int)
-	y.SetBytes(yBytes)
-
-	pub := new(ecdsa.PublicKey)
-	pub.X = x
-	pub.Y = y
+func isLegacySigver(sigver string) bool {
+	return sigver == "" || sigver == SigVerLegacy
+}
 
-	pub.Curve = elliptic.P256()
+// RequireCanonicalSig 는 호출 정보와 트랜잭션 데이터까지 서명한 canonical 서명(sigver 2, 3)인지 확인한다.
+// 기존 방식은 publickey 와 txtime 만 서명하므로 원장에 남은 봉투의 transdata 를 바꿔서 다시 제출할 수 있다.
+// 키 관리처럼 봉투가 재사용되면 계정을 빼앗기는 작업에서 사용한다.
+func RequireCanonicalSig(walletParams *WalletParams) error {
+	if isLegacySigver(walletParams.Sigver) {
+		return model.NewCustomError(model.SignatureErrorType, model.SigReasonSigver, "this operation requires sigver "+SigVerCanonical+" or "+SigVerSHA256)
+	}
+	return nil
+}
 
-	return pub
+// signedMessage 는 Sigver 에 따른 서명 대상 메시지를 반환한다.
+func signedMessage(walletMeta WalletMeta, sigCtx SigContext) (string, error) {
+	switch {
+	case isLegacySigver(walletMeta.Sigver):
+		// 기존 방식은 nonce 를 서명하지 않으므로 nonce 를 신뢰할 수 없다.
+		if walletMeta.Nonce != "" {
+			return "", model.NewCustomError(model.NonceErrorType, "Sigver", "nonce requires sigver "+SigVerCanonical)
+		}
+		return walletMeta.Publickey + walletMeta.Txtime, nil
+	case walletMeta.Sigver == SigVerCanonical || walletMeta.Sigver == SigVerSHA256:
+		if sigCtx.Function == "" || sigCtx.Chaincode == "" || sigCtx.ExecFunction == "" || sigCtx.ExecChaincode == "" {
+			return "", model.NewCustomError(model.MandatoryPrameterErrorType, "SigContext", "function and chaincode name are required for sigver "+walletMeta.Sigver)
+		}
+		return CanonicalMessage(walletMeta, sigCtx), nil
+	default:
+		return "", model.NewCustomError(model.SignatureErrorType, model.SigReasonSigver, "unsupported sigver "+walletMeta.Sigver)
+	}
 }
 
 const alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
@@ -174,11 +211,49 @@ type ecdsaSignature struct {
 	R, S *big.Int
 }
 
-func verifyMySig(pub *ecdsa.PublicKey, msg string, sig []byte) bool {
-	digest := sha1.Sum([]byte(msg))
+// signatureDigest 는 ECDSA 서명 대상 digest 를 만든다.
+// SigVerSHA256 은 메시지의 SHA-256 을 그대로 쓰고, 이전 버전은 SHA-256 hex 문자열의 SHA-1 을 쓴다.
+func signatureDigest(sigver string, orgSigMsg string) []byte {
+	orgSigMsgHash := sha256.Sum256([]byte(orgSigMsg))
+	if sigver == SigVerSHA256 {
+		return orgSigMsgHash[:]
+	}
+	sigMsg := fmt.Sprintf("%x", orgSigMsgHash)
+	digest := sha1.Sum([]byte(sigMsg))
+	return digest[:]
+}
 
+// verifyMySig 는 DER 서명을 엄격하게 디코딩해서 검증한다.
+// 실패하면 TypeName 에 model.SigReason* 사유 코드를 담은 SignatureErrorType 오류를 반환한다.
+func verifyMySig(pub *ecdsa.PublicKey, digest []byte, sig []byte, enforceLowS bool) error {
 	var esig ecdsaSignature
-	asn1.Unmarshal(sig, &esig)
+	rest, err := asn1.Unmarshal(sig, &esig)
+	if err != nil {
+		return model.NewCustomError(model.SignatureErrorType, model.SigReasonDER, err.Error())
+	}
+	if len(rest) > 0 {
+		return model.NewCustomError(model.SignatureErrorType, model.SigReasonTrailingData, "trailing data after DER signature")
+	}
+	// DER 은 인코딩이 하나뿐이므로 다시 인코딩한 값과 같아야 한다.
+	if canonical, err := asn1.Marshal(esig); err != nil || !bytes.Equal(canonical, sig) {
+		return model.NewCustomError(model.SignatureErrorType, model.SigReasonNonCanonicalDER, "signature is not canonical DER")
+	}
+
+	n := pub.Curve.Params().N
+	if esig.R.Sign() <= 0 || esig.S.Sign() <= 0 || esig.R.Cmp(n) >= 0 || esig.S.Cmp(n) >= 0 {
+		return model.NewCustomError(model.SignatureErrorType, model.SigReasonOutOfRange, "signature R, S must be in [1, N-1]")
+	}
 
-	return ecdsa.Verify(pub, digest[:], esig.R, esig.S)
+	halfN := new(big.Int).Rsh(n, 1)
+	if esig.S.Cmp(halfN) > 0 {
+		if enforceLowS {
+			return model.NewCustomError(model.SignatureErrorType, model.SigReasonHighS, "signature S must be low-S")
+		}
+		esig.S = new(big.Int).Sub(n, esig.S)
+	}
+
+	if !ecdsa.Verify(pub, digest, esig.R, esig.S) {
+		return model.NewCustomError(model.SignatureErrorType, model.SigReasonMismatch, "Signature is Fail")
+	}
+	return nil
 }
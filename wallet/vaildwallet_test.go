@@ -0,0 +1,328 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/jinsan74/Erc20/model"
+)
+
+// 고정 테스트 벡터. canonical 벡터는 아래 vectorCtx 로 서명되었다.
+const (
+	legacyP256Pub = "00B413D9FAD1FC5B50CB93B9B7C554CE5B3A449115AA361B24B46D9156E0512E25:00E9FC5AAAF269E7EB0BD5003DC3C9F7FF159522FE62E2F23E7F717481809044A5"
+	legacyP256Sig = "3045022100A06221FFA0C44A0A12051A1C8694D4BA475C3D121D069D6EA6AF0F7576426E9A02206DECA4CCB32A0A86913281DE0C05A27DAF16E82F894E6D34E6E1488CE4AE5B8F"
+
+	p256Pub = "7D59438BB7366C7AA36E0ACBBD981B69D4BA908F3AB877A1A95D3B6C677A901A:A063DE04D245FCBAD457091E8EE138B6A5DFABEEC1F68D5010EDA29B5036E09A"
+	p256Sig = "304502210088333a833edd7ccd362dfbf9c9f2658679706e55b2ebb04c01a1faf987a53e2902204b86fad08cc1495e664c9ab071a52644ea4ea2f83a01ea66a87885afa240d570"
+	// p256Pub 키로 SigVerSHA256 서명
+	p256SHA256Sig = "3044022002080783bc9ef5c2fdb207bc104664ace05869b6c9cc84ab5fd3dcfb87e9061c02207e0be647195f44220385669d3d2fd252c3d02f114dedc83bb0c8cb1fb9e74e16"
+
+	secp256k1Pub = "C7D89E5594F3020A547AD9C336CF5930AD35EEC7C453144EB2B3175F79EF7F5B:7711E60089895EECBFF401B304BCC0961F70EB17EBE0B8EF672EFC232689D055"
+	secp256k1Sig = "304402204e30c205f1724862c7275a635aa5fb185f560822e4c0e5c1d11a0ebafd9c79b60220073e74b613a68c5eb2162a28a613e2aeef0d27ebe93d52cfc79304ced8dbee56"
+
+	ed25519Pub = "aa17438bde8ec009f160667c62faa2e3d9bc794e3f049b410bae6c2a39a61a82"
+	ed25519Sig = "8e065af214c019cdce7bd1c0076a161bfdb85366280aa7e477bff3fed1ae2d411a233e547e9587ee27cac17a69d992d6552995e048ef1383de4631d4fa2e8a01"
+)
+
+var vectorCtx = SigContext{Function: "transfer", Channel: "mychannel", Chaincode: "erc20", ExecFunction: "transfer", ExecChaincode: "erc20"}
+
+func canonicalVector(pub, sig, curve, keytype string) WalletMeta {
+	return WalletMeta{Publickey: pub, Sigmsg: sig, Curve: curve, Keytype: keytype, Txtime: "1700000000", Transdata: "bob,10", Nonce: "1", Sigver: SigVerCanonical}
+}
+
+func TestVaildWalletVectors(t *testing.T) {
+	tampered := canonicalVector(ed25519Pub, ed25519Sig, "", KeyTypeEd25519)
+	tampered.Transdata = "mallory,10"
+	shortKey := canonicalVector(ed25519Pub[:62], ed25519Sig, "", KeyTypeEd25519)
+	legacyEd := canonicalVector(ed25519Pub, ed25519Sig, "", KeyTypeEd25519)
+	legacyEd.Sigver = SigVerLegacy
+	legacyEd.Nonce = ""
+	ecdsaAsEd := canonicalVector(p256Pub, p256Sig, "", KeyTypeEd25519)
+	edWithCurve := canonicalVector(ed25519Pub, ed25519Sig, CurveSecp256k1, KeyTypeEd25519)
+	otherCtx := canonicalVector(p256Pub, p256Sig, "", "")
+	sha256Vector := canonicalVector(p256Pub, p256SHA256Sig, "", "")
+	sha256Vector.Sigver = SigVerSHA256
+	sha256AsSHA1 := canonicalVector(p256Pub, p256SHA256Sig, "", "")
+
+	cases := []struct {
+		name      string
+		meta      WalletMeta
+		ctx       SigContext
+		address   string
+		legacy    string
+		errorType string
+	}{
+		{"legacy p256", WalletMeta{Publickey: legacyP256Pub, Sigmsg: legacyP256Sig, Txtime: "1609128127", Transdata: "1,2"}, vectorCtx, "tcccaeH7kVa9qZQANJt7fC7gxNj3ARTPKk", "BV8oLGiPd8qTGsEqqJ7b9V7X29VuQcea2C", ""},
+		{"canonical p256", canonicalVector(p256Pub, p256Sig, "", ""), vectorCtx, "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg", "TGccp9gBRU16KGPGoJafJuWep1uzA4Gzjq", ""},
+		{"canonical p256 explicit keytype", canonicalVector(p256Pub, p256Sig, CurveP256, KeyTypeECDSA), vectorCtx, "", "", model.SignatureErrorType},
+		{"sha256 p256", sha256Vector, vectorCtx, "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg", "TGccp9gBRU16KGPGoJafJuWep1uzA4Gzjq", ""},
+		{"sha256 signature as sigver 2", sha256AsSHA1, vectorCtx, "", "", model.SignatureErrorType},
+		{"canonical secp256k1", canonicalVector(secp256k1Pub, secp256k1Sig, CurveSecp256k1, ""), vectorCtx, "uEHhePkzGgQmsAndX9MQTvviBMGNdF3YP9", "", ""},
+		{"ed25519", canonicalVector(ed25519Pub, ed25519Sig, "", KeyTypeEd25519), vectorCtx, "uUjYR1DtbzXbD6cWN4qgrwTwnd7ShTgGZ1", "", ""},
+		{"ed25519 tampered transdata", tampered, vectorCtx, "", "", model.SignatureErrorType},
+		{"ed25519 short key", shortKey, vectorCtx, "", "", model.PublicKeyErrorType},
+		{"ed25519 legacy sigver", legacyEd, vectorCtx, "", "", model.SignatureErrorType},
+		{"ed25519 with ecdsa key", ecdsaAsEd, vectorCtx, "", "", model.HexDecodeErrorType},
+		{"ed25519 with curve", edWithCurve, vectorCtx, "", "", model.PublicKeyErrorType},
+		{"p256 other chaincode", otherCtx, SigContext{Function: "transfer", Channel: "mychannel", Chaincode: "other", ExecFunction: "transfer", ExecChaincode: "other"}, "", "", model.SignatureErrorType},
+		{"p256 forwarded to approve", otherCtx, SigContext{Function: "transfer", Channel: "mychannel", Chaincode: "erc20", ExecFunction: "approve", ExecChaincode: "erc20"}, "", "", model.SignatureErrorType},
+		{"p256 forwarded to other token", otherCtx, SigContext{Function: "transfer", Channel: "mychannel", Chaincode: "erc20", ExecFunction: "transfer", ExecChaincode: "other"}, "", "", model.SignatureErrorType},
+		{"p256 without executing function", otherCtx, SigContext{Function: "transfer", Channel: "mychannel", Chaincode: "erc20"}, "", "", model.MandatoryPrameterErrorType},
+		{"unknown keytype", canonicalVector(p256Pub, p256Sig, "", "rsa"), vectorCtx, "", "", model.PublicKeyErrorType},
+	}
+
+	for _, tc := range cases {
+		walletParams, err := vaildWallet(tc.meta, tc.ctx, model.TxTimeConfig{Disabled: true}, model.SigConfig{}, nil)
+		if tc.errorType != "" {
+			if customErr := model.ToCustomError(err); customErr == nil || customErr.ErrorType != tc.errorType {
+				t.Errorf("%s: got error %v, want %s", tc.name, err, tc.errorType)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if walletParams.Address != tc.address {
+			t.Errorf("%s: address %s, want %s", tc.name, walletParams.Address, tc.address)
+		}
+		if walletParams.LegacyAddress != tc.legacy {
+			t.Errorf("%s: legacy address %s, want %s", tc.name, walletParams.LegacyAddress, tc.legacy)
+		}
+	}
+}
+
+func TestVerifyMySigStrictDER(t *testing.T) {
+	pub, err := parseECDSAPublicKey(p256Pub, curveRegistry[CurveP256])
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := canonicalVector(p256Pub, p256Sig, "", "")
+	digest := signatureDigest(meta.Sigver, CanonicalMessage(meta, vectorCtx))
+
+	sig, _ := hex.DecodeString(p256Sig)
+	var esig ecdsaSignature
+	asn1.Unmarshal(sig, &esig)
+	highS, _ := asn1.Marshal(ecdsaSignature{R: esig.R, S: new(big.Int).Sub(pub.Curve.Params().N, esig.S)})
+	longLength := append([]byte{0x30, 0x81}, sig[1:]...)
+
+	cases := []struct {
+		name        string
+		sig         []byte
+		digest      []byte
+		enforceLowS bool
+		reason      string
+	}{
+		{"valid", sig, digest, true, ""},
+		{"trailing data", append(append([]byte{}, sig...), 0x00), digest, true, model.SigReasonTrailingData},
+		{"truncated", sig[:len(sig)-1], digest, true, model.SigReasonDER},
+		{"non-minimal length", longLength, digest, true, model.SigReasonDER},
+		{"zero R", []byte{0x30, 0x06, 0x02, 0x01, 0x00, 0x02, 0x01, 0x01}, digest, true, model.SigReasonOutOfRange},
+		{"high S enforced", highS, digest, true, model.SigReasonHighS},
+		{"high S normalised", highS, digest, false, ""},
+		{"wrong digest", sig, digest[1:], true, model.SigReasonMismatch},
+	}
+
+	for _, tc := range cases {
+		err := verifyMySig(pub, tc.digest, tc.sig, tc.enforceLowS)
+		if tc.reason == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+			continue
+		}
+		customErr := model.ToCustomError(err)
+		if customErr == nil || customErr.ErrorType != model.SignatureErrorType || customErr.TypeName != tc.reason {
+			t.Errorf("%s: got %v, want %s", tc.name, err, tc.reason)
+		}
+	}
+}
+
+func TestDecodeAddress(t *testing.T) {
+	cases := []struct {
+		name    string
+		address string
+		version byte
+		valid   bool
+	}{
+		{"p256", "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg", AddressVersionP256, true},
+		{"secp256k1", "uEHhePkzGgQmsAndX9MQTvviBMGNdF3YP9", AddressVersionSecp256k1, true},
+		{"ed25519", "uUjYR1DtbzXbD6cWN4qgrwTwnd7ShTgGZ1", AddressVersionEd25519, true},
+		{"legacy", "BV8oLGiPd8qTGsEqqJ7b9V7X29VuQcea2C", 0, false},
+		{"typo", "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBh", 0, false},
+		{"not base58", "tp2kD5fcscYztAfUABjNM2iCduAzNZdsB0", 0, false},
+		{"short", "tp2kD5fcscYztAfUABjNM2iCduAzNZds", 0, false},
+		{"empty", "", 0, false},
+	}
+
+	for _, tc := range cases {
+		version, hash, err := DecodeAddress(tc.address)
+		if !tc.valid {
+			if customErr := model.ToCustomError(err); customErr == nil || customErr.ErrorType != model.AddressErrorType {
+				t.Errorf("%s: got error %v, want %s", tc.name, err, model.AddressErrorType)
+			}
+			continue
+		}
+		if err != nil || version != tc.version || len(hash) != addressHashLen {
+			t.Errorf("%s: version 0x%02x, hash %x, err %v", tc.name, version, hash, err)
+		}
+	}
+}
+
+func TestAddressFromPublicKey(t *testing.T) {
+	cases := []struct {
+		name      string
+		publickey string
+		curve     string
+		keytype   string
+		address   string
+	}{
+		{"p256", p256Pub, "", "", "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg"},
+		{"secp256k1", secp256k1Pub, CurveSecp256k1, KeyTypeECDSA, "uEHhePkzGgQmsAndX9MQTvviBMGNdF3YP9"},
+		{"ed25519", ed25519Pub, "", KeyTypeEd25519, "uUjYR1DtbzXbD6cWN4qgrwTwnd7ShTgGZ1"},
+	}
+
+	for _, tc := range cases {
+		address, err := AddressFromPublicKey(tc.publickey, tc.curve, tc.keytype)
+		if err != nil || address != tc.address {
+			t.Errorf("%s: address %s, err %v, want %s", tc.name, address, err, tc.address)
+		}
+		if err := ValidateAddress(address); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+
+	if _, err := AddressFromPublicKey(p256Pub, CurveSecp256k1, ""); err == nil {
+		t.Error("p256 key accepted as secp256k1")
+	}
+}
+
+// encodePublicKey 는 "X:Y" 공개키를 SEC1 압축/비압축, hex DER, PEM SubjectPublicKeyInfo 로 인코딩한다.
+func encodePublicKey(t *testing.T, publicKeyStr string, curveID string) []string {
+	spec := curveRegistry[curveID]
+	pub, err := parseECDSAPublicKey(publicKeyStr, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xy := strings.Split(publicKeyStr, ":")
+	uncompressed, _ := hex.DecodeString("04" + xy[0] + xy[1])
+	curveOID, _ := asn1.Marshal(spec.oid)
+	der, err := asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: curveOID}},
+		PublicKey: asn1.BitString{Bytes: uncompressed, BitLength: 8 * len(uncompressed)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []string{
+		hex.EncodeToString(compressedKey(pub)),
+		hex.EncodeToString(uncompressed),
+		hex.EncodeToString(der),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}
+}
+
+func TestPublicKeyEncodings(t *testing.T) {
+	cases := []struct {
+		name      string
+		publickey string
+		curve     string
+		address   string
+	}{
+		{"p256", p256Pub, CurveP256, "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg"},
+		{"secp256k1", secp256k1Pub, CurveSecp256k1, "uEHhePkzGgQmsAndX9MQTvviBMGNdF3YP9"},
+	}
+	for _, tc := range cases {
+		for _, encoded := range encodePublicKey(t, tc.publickey, tc.curve) {
+			address, err := AddressFromPublicKey(encoded, tc.curve, "")
+			if err != nil || address != tc.address {
+				t.Errorf("%s %q: address %s, err %v, want %s", tc.name, encoded, address, err, tc.address)
+			}
+		}
+	}
+
+	// P-256 의 x509 DER 도 같은 주소
+	pub, _ := parseECDSAPublicKey(p256Pub, curveRegistry[CurveP256])
+	der, _ := x509.MarshalPKIXPublicKey(pub)
+	if address, err := AddressFromPublicKey(hex.EncodeToString(der), "", ""); err != nil || address != "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg" {
+		t.Errorf("x509 DER: address %s, err %v", address, err)
+	}
+
+	edPub, _ := hex.DecodeString(ed25519Pub)
+	edDER, _ := x509.MarshalPKIXPublicKey(ed25519.PublicKey(edPub))
+	edPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: edDER}))
+	for _, encoded := range []string{hex.EncodeToString(edDER), edPEM} {
+		if address, err := AddressFromPublicKey(encoded, "", KeyTypeEd25519); err != nil || address != "uUjYR1DtbzXbD6cWN4qgrwTwnd7ShTgGZ1" {
+			t.Errorf("ed25519 %q: address %s, err %v", encoded, address, err)
+		}
+	}
+
+	p256Encodings := encodePublicKey(t, p256Pub, CurveP256)
+	k1Encodings := encodePublicKey(t, secp256k1Pub, CurveSecp256k1)
+	xOutOfRange := "FFFFFFFF00000001000000000000000000000000FFFFFFFFFFFFFFFFFFFFFFFF" + strings.Split(p256Pub, ":")[0][64:]
+	invalid := []struct {
+		name      string
+		publickey string
+		curve     string
+		errorType string
+	}{
+		{"secp256k1 SPKI as p256", k1Encodings[2], CurveP256, model.PublicKeyErrorType},
+		{"p256 PEM as secp256k1", p256Encodings[3], CurveSecp256k1, model.PublicKeyErrorType},
+		{"bad prefix", "05" + p256Encodings[0][2:], CurveP256, model.PublicKeyErrorType},
+		{"truncated", p256Encodings[0][:64], CurveP256, model.PublicKeyErrorType},
+		{"x not on curve", "02" + strings.Repeat("00", 31) + "01", CurveP256, model.CurvePointErrorType},
+		{"x out of range", "02" + xOutOfRange, CurveP256, model.CurvePointErrorType},
+		{"pem trailing data", p256Encodings[3] + "junk", CurveP256, model.PublicKeyErrorType},
+		{"not hex", "zz", CurveP256, model.HexDecodeErrorType},
+	}
+	for _, tc := range invalid {
+		_, err := AddressFromPublicKey(tc.publickey, tc.curve, "")
+		if customErr := model.ToCustomError(err); customErr == nil || customErr.ErrorType != tc.errorType {
+			t.Errorf("%s: got error %v, want %s", tc.name, err, tc.errorType)
+		}
+	}
+}
+
+func TestMultisigAddress(t *testing.T) {
+	p256Addr, k1Addr, edAddr := "tp2kD5fcscYztAfUABjNM2iCduAzNZdsBg", "uEHhePkzGgQmsAndX9MQTvviBMGNdF3YP9", "uUjYR1DtbzXbD6cWN4qgrwTwnd7ShTgGZ1"
+
+	address, normalized, err := MultisigAddress(MultisigPolicy{Threshold: 2, Members: []string{edAddr, p256Addr, k1Addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, _, err := DecodeAddress(address); err != nil || version != AddressVersionMultisig {
+		t.Errorf("multisig address %s version 0x%02x, err %v", address, version, err)
+	}
+	if reordered, _, _ := MultisigAddress(MultisigPolicy{Threshold: 2, Members: []string{k1Addr, edAddr, p256Addr}}); reordered != address {
+		t.Errorf("member order changed address: %s, %s", reordered, address)
+	}
+	if other, _, _ := MultisigAddress(MultisigPolicy{Threshold: 3, Members: normalized.Members}); other == address {
+		t.Error("threshold does not change address")
+	}
+
+	invalid := []struct {
+		name      string
+		policy    MultisigPolicy
+		errorType string
+	}{
+		{"no members", MultisigPolicy{Threshold: 1}, model.MandatoryPrameterErrorType},
+		{"zero threshold", MultisigPolicy{Threshold: 0, Members: []string{p256Addr}}, model.MandatoryPrameterErrorType},
+		{"threshold above members", MultisigPolicy{Threshold: 3, Members: []string{p256Addr, k1Addr}}, model.MandatoryPrameterErrorType},
+		{"duplicate member", MultisigPolicy{Threshold: 1, Members: []string{p256Addr, p256Addr}}, model.AddressErrorType},
+		{"legacy member", MultisigPolicy{Threshold: 1, Members: []string{"BV8oLGiPd8qTGsEqqJ7b9V7X29VuQcea2C"}}, model.AddressErrorType},
+		{"nested multisig", MultisigPolicy{Threshold: 1, Members: []string{address}}, model.AddressErrorType},
+	}
+	for _, tc := range invalid {
+		_, _, err := MultisigAddress(tc.policy)
+		if customErr := model.ToCustomError(err); customErr == nil || customErr.ErrorType != tc.errorType {
+			t.Errorf("%s: got error %v, want %s", tc.name, err, tc.errorType)
+		}
+	}
+}